@@ -0,0 +1,147 @@
+package redis4rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// CachedEmbedder wraps an Embedder and keeps its vectors in Redis under
+	// {prefix}:{model}:sha256(text), encoded as FLOAT64 blobs by vector2string.
+	// The cache is best effort: Redis errors fall through to the wrapped embedder.
+	CachedEmbedder struct {
+		redisCli redis.UniversalClient
+		model    string
+		embedder Embedder
+		prefix   string
+		ttl      time.Duration
+		maxSize  int64
+
+		hits   atomic.Uint64
+		misses atomic.Uint64
+	}
+
+	CachedEmbedderOption func(*CachedEmbedder)
+)
+
+// WithCachePrefix sets the key prefix of cached vectors, "embedding" by default.
+func WithCachePrefix(prefix string) CachedEmbedderOption {
+	return func(c *CachedEmbedder) { c.prefix = prefix }
+}
+
+// WithCacheTTL expires cached vectors ttl after they were last used; they never expire by default.
+func WithCacheTTL(ttl time.Duration) CachedEmbedderOption {
+	return func(c *CachedEmbedder) { c.ttl = ttl }
+}
+
+// WithCacheMaxSize evicts the least recently used vectors once more than size are cached.
+func WithCacheMaxSize(size int64) CachedEmbedderOption {
+	return func(c *CachedEmbedder) { c.maxSize = size }
+}
+
+func NewCachedEmbedder(cli redis.UniversalClient, model string, embedder Embedder, opts ...CachedEmbedderOption) *CachedEmbedder {
+	c := &CachedEmbedder{
+		redisCli: cli,
+		model:    model,
+		embedder: embedder,
+		prefix:   "embedding",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Embed is an Embedder: it returns the cached vector of text, embedding and caching it on a miss.
+func (c *CachedEmbedder) Embed(ctx context.Context, text string) (vec []float64, err error) {
+	key := c.key(text)
+	var get *redis.StringCmd
+	if c.ttl > 0 {
+		// a hit restarts the TTL, so that the LRU set, scored by last use, tells which keys expired
+		get = c.redisCli.GetEx(ctx, key, c.ttl)
+	} else {
+		get = c.redisCli.Get(ctx, key)
+	}
+	if val, err := get.Result(); err == nil {
+		c.hits.Add(1)
+		c.touch(ctx, key)
+		return string2vector(val, VectorTypeFloat64), nil
+	}
+	c.misses.Add(1)
+	if vec, err = c.embedder(ctx, text); err != nil {
+		return
+	}
+	c.put(ctx, key, vec)
+	return
+}
+
+// Stats reports cache hits and misses since the CachedEmbedder was created.
+func (c *CachedEmbedder) Stats() (hits, misses uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Clear removes every vector cached for the model.
+func (c *CachedEmbedder) Clear(ctx context.Context) error {
+	err := scanKeys(ctx, c.redisCli, escapeGlob(c.prefix+":"+c.model+":")+"*", func(keys []string) error {
+		_, err := delKeys(ctx, c.redisCli, keys)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return c.redisCli.Del(ctx, c.lruKey()).Err()
+}
+
+// key pattern: {CachedEmbedder.prefix}:{CachedEmbedder.model}:sha256(text)
+func (c *CachedEmbedder) key(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("%s:%s:%s", c.prefix, c.model, hex.EncodeToString(hash[:]))
+}
+
+// lruKey is a sorted set of cached keys scored by last access, used to enforce maxSize.
+func (c *CachedEmbedder) lruKey() string {
+	return fmt.Sprintf("%s:%s:#lru", c.prefix, c.model)
+}
+
+func (c *CachedEmbedder) touch(ctx context.Context, key string) {
+	if c.maxSize <= 0 {
+		return
+	}
+	c.redisCli.ZAdd(ctx, c.lruKey(), redis.Z{Score: float64(time.Now().UnixMilli()), Member: key})
+}
+
+func (c *CachedEmbedder) put(ctx context.Context, key string, vec []float64) {
+	if err := c.redisCli.Set(ctx, key, vector2string(vec, VectorTypeFloat64), c.ttl).Err(); err != nil || c.maxSize <= 0 {
+		return
+	}
+	now := time.Now()
+	lru := c.lruKey()
+	pipeline := c.redisCli.Pipeline()
+	pipeline.ZAdd(ctx, lru, redis.Z{Score: float64(now.UnixMilli()), Member: key})
+	if c.ttl > 0 {
+		// entries older than the TTL point at keys that already expired
+		pipeline.ZRemRangeByScore(ctx, lru, "-inf", "("+strconv.FormatInt(now.Add(-c.ttl).UnixMilli(), 10))
+	}
+	size := pipeline.ZCard(ctx, lru)
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return
+	}
+	if excess := size.Val() - c.maxSize; excess > 0 {
+		evicted, err := c.redisCli.ZPopMin(ctx, lru, excess).Result()
+		if err != nil {
+			return
+		}
+		keys := make([]string, len(evicted))
+		for i, z := range evicted {
+			keys[i] = z.Member.(string)
+		}
+		delKeys(ctx, c.redisCli, keys)
+	}
+}
@@ -0,0 +1,57 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestVectorRoundTrip(t *testing.T) {
+	vec := []float64{0.5, -1.25, 3}
+	should.Equal(t, vec, string2vector(vector2string(vec, VectorTypeFloat64), VectorTypeFloat64))
+	should.Equal(t, vec, string2vector(vector2string(vec, VectorTypeFloat32), VectorTypeFloat32))
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	ctx := context.Background()
+	redisCli := testRedis(t)
+
+	calls := 0
+	embedder := NewCachedEmbedder(redisCli, "test_cached_embedder", func(ctx context.Context, text string) ([]float64, error) {
+		calls++
+		return localEmbedder.Embedding(ctx, text)
+	}, WithCacheMaxSize(2))
+	should.Nil(t, embedder.Clear(ctx))
+
+	for range 2 {
+		vec, err := embedder.Embed(ctx, "咱俩谁跟谁呀。")
+		should.Nil(t, err)
+		should.Equal(t, localEmbedder["咱俩谁跟谁呀。"], vec)
+	}
+	should.Equal(t, 1, calls)
+	hits, misses := embedder.Stats()
+	should.Equal(t, uint64(1), hits)
+	should.Equal(t, uint64(1), misses)
+
+	// the first text is the least recently used one once a third is cached
+	for _, text := range []string{"我俩谁跟谁呀。", "咱俩关系很好。"} {
+		_, err := embedder.Embed(ctx, text)
+		should.Nil(t, err)
+	}
+	should.Equal(t, int64(2), redisCli.ZCard(ctx, embedder.lruKey()).Val())
+	should.Equal(t, int64(0), redisCli.Exists(ctx, embedder.key("咱俩谁跟谁呀。")).Val())
+	should.Nil(t, embedder.Clear(ctx))
+
+	// a hit restarts the TTL
+	embedder = NewCachedEmbedder(redisCli, "test_cached_embedder", localEmbedder.Embedding, WithCacheTTL(time.Hour), WithCacheMaxSize(2))
+	_, err := embedder.Embed(ctx, "咱俩谁跟谁呀。")
+	should.Nil(t, err)
+	should.Nil(t, redisCli.Expire(ctx, embedder.key("咱俩谁跟谁呀。"), time.Minute).Err())
+	_, err = embedder.Embed(ctx, "咱俩谁跟谁呀。")
+	should.Nil(t, err)
+	should.Greater(t, redisCli.TTL(ctx, embedder.key("咱俩谁跟谁呀。")).Val(), 59*time.Minute)
+
+	should.Nil(t, embedder.Clear(ctx))
+}
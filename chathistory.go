@@ -17,6 +17,8 @@ var (
 		ChatMessageContent,
 		ChatMessageTimestamp,
 		ChatMessageContentVec,
+		EmbeddingModelId,
+		EmbeddingModelVersion,
 	}
 
 	ChatMessageDefaultReturn = []redis.FTSearchReturn{
@@ -42,23 +44,19 @@ var (
 	ChatMessageTimestamp = &redis.FieldSchema{
 		FieldName: "$.timestamp", As: "timestamp", FieldType: redis.SearchFieldTypeNumeric, Sortable: true,
 	}
-	ChatMessageContentVec = &redis.FieldSchema{
-		FieldName: "$.content_vec", As: "content_vec", FieldType: redis.SearchFieldTypeVector,
-		VectorArgs: &redis.FTVectorArgs{
-			FlatOptions: &redis.FTFlatOptions{
-				Type:           "FLOAT64",
-				Dim:            1024,
-				DistanceMetric: "COSINE",
-			},
-		},
-	}
+	ChatMessageContentVec = DefaultVectorConfig.Field("$.content_vec", "content_vec")
 )
 
 type (
 	ChatHistory struct {
 		indexName string
 		docPrefix string
-		redisCli  *redis.Client
+		redisCli  redis.UniversalClient
+		embedder  Embedder
+		batcher   BatchEmbedder
+		batchSize int
+		vector    VectorConfig
+		model     EmbeddingModel
 	}
 
 	ChatMessage struct {
@@ -70,24 +68,89 @@ type (
 	}
 )
 
+// ChatHistorySchema generates the chat history schema with cfg as its vector field.
+func (cfg VectorConfig) ChatHistorySchema() []*redis.FieldSchema {
+	return []*redis.FieldSchema{
+		ChatMessageUserId,
+		ChatMessageSessionId,
+		ChatMessageType,
+		ChatMessageContent,
+		ChatMessageTimestamp,
+		cfg.Field(ChatMessageContentVec.FieldName, ChatMessageContentVec.As),
+		EmbeddingModelId,
+		EmbeddingModelVersion,
+	}
+}
+
+func NewChatHistory(ctx context.Context, opts ...Option) (*ChatHistory, error) {
+	o := newOptions("idx:chat_history", "doc:chat_history", VectorConfig.ChatHistorySchema, opts...)
+	if err := o.retrieverOnly("ChatHistory"); err != nil {
+		return nil, err
+	}
+	if err := o.init(ctx); err != nil {
+		return nil, err
+	}
+	return &ChatHistory{
+		indexName: o.indexName,
+		docPrefix: o.docPrefix,
+		redisCli:  o.redisCli,
+		embedder:  o.embedder,
+		batcher:   o.batcher,
+		batchSize: o.batchSize,
+		vector:    *o.vector,
+		model:     o.model,
+	}, nil
+}
+
 func (history *ChatHistory) Add(ctx context.Context, msg *ChatMessage, embedder Embedder) (err error) {
 	var vec []float64
-	if vec, err = embedder(ctx, msg.Content); err != nil {
+	if vec, err = embed(ctx, msg.Content, embedder, history.embedder); err != nil {
 		return
 	}
-	var jsondata []byte
-	if jsondata, err = json.Marshal(msg); err != nil {
+	pipeline := history.redisCli.Pipeline()
+	if err = history.write(ctx, pipeline, msg, vec); err != nil {
 		return
 	}
-	pipeline := history.redisCli.Pipeline()
-	// key pattern: {ChatHistory.DocPrefix}:{Message.UserId}:{Message.SessionId}
-	key := fmt.Sprintf("%s:%s:%s:%d", history.docPrefix, msg.UserId, msg.SessionId, msg.Timestamp)
-	pipeline.JSONSet(ctx, key, "$", string(jsondata))
-	pipeline.JSONSet(ctx, key, ChatMessageContentVec.FieldName, vec)
 	_, err = pipeline.Exec(ctx)
 	return err
 }
 
+// AddBatch embeds and adds msgs in chunks of the configured batch size, one pipeline per chunk.
+func (history *ChatHistory) AddBatch(ctx context.Context, msgs []*ChatMessage, embedder BatchEmbedder) error {
+	texts := make([]string, len(msgs))
+	for i, msg := range msgs {
+		texts[i] = msg.Content
+	}
+	return forEachBatch(ctx, texts, history.batchSize, batchOf(embedder, history.batcher, history.embedder), func(lo, hi int, vecs [][]float64) error {
+		pipeline := history.redisCli.Pipeline()
+		for i, msg := range msgs[lo:hi] {
+			if err := history.write(ctx, pipeline, msg, vecs[i]); err != nil {
+				return err
+			}
+		}
+		_, err := pipeline.Exec(ctx)
+		return err
+	})
+}
+
+func (history *ChatHistory) write(ctx context.Context, pipeline redis.Pipeliner, msg *ChatMessage, vec []float64) error {
+	vec, err := history.vector.prepare(vec)
+	if err != nil {
+		return err
+	}
+	jsondata, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	key := history.key(msg)
+	pipeline.JSONSet(ctx, key, "$", string(jsondata))
+	pipeline.JSONSet(ctx, key, ChatMessageContentVec.FieldName, history.vector.jsonValue(vec))
+	if len(history.model.ID) > 0 {
+		pipeline.JSONSet(ctx, key, "$.embedding", history.model)
+	}
+	return nil
+}
+
 func (history *ChatHistory) ListByUserId(ctx context.Context, from int64, userId string) (msgs []*ChatMessage, err error) {
 	return history.list(ctx, from, ChatMessageUserId.As, userId)
 }
@@ -96,12 +159,12 @@ func (history *ChatHistory) ListBySessionId(ctx context.Context, from int64, ses
 	return history.list(ctx, from, ChatMessageSessionId.As, sessionId)
 }
 
-func (history *ChatHistory) SearchWithUserId(ctx context.Context, from int64, userId string, text string, embedder Embedder) (msgs []*ChatMessage, err error) {
-	return history.search(ctx, from, ChatMessageUserId.As, userId, text, embedder)
+func (history *ChatHistory) SearchWithUserId(ctx context.Context, from int64, userId string, text string, embedder Embedder, searchOpts ...SearchOption) (msgs []*ScoredChatMessage, err error) {
+	return history.search(ctx, from, ChatMessageUserId.As, userId, text, embedder, searchOpts...)
 }
 
-func (history *ChatHistory) SearchWithSessionId(ctx context.Context, from int64, sessionId string, text string, embedder Embedder) (msgs []*ChatMessage, err error) {
-	return history.search(ctx, from, ChatMessageUserId.As, sessionId, text, embedder)
+func (history *ChatHistory) SearchWithSessionId(ctx context.Context, from int64, sessionId string, text string, embedder Embedder, searchOpts ...SearchOption) (msgs []*ScoredChatMessage, err error) {
+	return history.search(ctx, from, ChatMessageSessionId.As, sessionId, text, embedder, searchOpts...)
 }
 
 func (history *ChatHistory) DeleteByUserId(ctx context.Context, userId string) error {
@@ -113,11 +176,15 @@ func (history *ChatHistory) DeleteBySessionId(ctx context.Context, sessionId str
 }
 
 func (history *ChatHistory) list(ctx context.Context, from int64, key, val string) (msgs []*ChatMessage, err error) {
+	var clause string
+	if clause, err = idClause(key, val); err != nil {
+		return
+	}
 	opts := &redis.FTSearchOptions{
 		Return:         ChatMessageDefaultReturn,
 		DialectVersion: 2,
 	}
-	query := fmt.Sprintf("@%s:%s @%s:[%d inf]", key, val, ChatMessageTimestamp.As, from)
+	query := fmt.Sprintf("%s @%s:[%d inf]", clause, ChatMessageTimestamp.As, from)
 	cmd := history.redisCli.FTSearchWithArgs(ctx, history.indexName, query, opts)
 	var result redis.FTSearchResult
 	if result, err = cmd.Result(); err == nil && result.Total > 0 {
@@ -129,50 +196,85 @@ func (history *ChatHistory) list(ctx context.Context, from int64, key, val strin
 	return
 }
 
-func (history *ChatHistory) search(ctx context.Context, from int64, key, val string, text string, embedder Embedder) (msgs []*ChatMessage, err error) {
+func (history *ChatHistory) search(ctx context.Context, from int64, key, val string, text string, embedder Embedder, searchOpts ...SearchOption) (msgs []*ScoredChatMessage, err error) {
+	so := newSearchOptions(searchOpts...)
+	if err = so.retrieverOnly("ChatHistory"); err != nil {
+		return
+	}
+	var clause string
+	if clause, err = idClause(key, val); err != nil {
+		return
+	}
 	var vec []float64
-	if vec, err = embedder(ctx, text); err != nil {
+	if vec, err = embed(ctx, text, embedder, history.embedder); err != nil {
+		return
+	}
+	if vec, err = history.vector.prepare(vec); err != nil {
 		return
 	}
 	opts := &redis.FTSearchOptions{
-		Return:         ChatMessageDefaultReturn,
+		Return:         withScore(ChatMessageDefaultReturn),
 		DialectVersion: 2,
-		Params:         map[string]interface{}{"vec": []byte(vector2string(vec))},
+		Params:         map[string]interface{}{"vec": []byte(vector2string(vec, history.vector.Type))},
 		SortBy: []redis.FTSearchSortBy{
-			{FieldName: "score", Asc: true},
+			{FieldName: scoreField, Asc: true},
 		},
 	}
-	filter := fmt.Sprintf("@%s:%s @%s:[%d inf]", key, val, ChatMessageTimestamp.As, from)
-	query := fmt.Sprintf("(%s)=>[KNN %d @%s $vec AS %s]", filter, 1, ChatMessageContentVec.As, "score")
+	filter := so.filter(fmt.Sprintf("%s @%s:[%d inf]", clause, ChatMessageTimestamp.As, from))
+	query := fmt.Sprintf("(%s)=>[%s]", filter, history.vector.knn(1, ChatMessageContentVec.As, scoreField))
 	cmd := history.redisCli.FTSearchWithArgs(ctx, history.indexName, query, opts)
 	var result redis.FTSearchResult
 	if result, err = cmd.Result(); err == nil && result.Total > 0 {
 		for _, doc := range result.Docs {
-			msg := parseChatMessage(&doc)
-			msgs = append(msgs, msg)
+			if score := history.vector.score(&doc); so.accept(history.vector, score.Distance) {
+				msgs = append(msgs, &ScoredChatMessage{ChatMessage: *parseChatMessage(&doc), Score: score})
+			}
 		}
 	}
 	return
 }
 
+// idClause matches the messages whose user or session id field is id, escaped as by Text.
+func idClause(field, id string) (string, error) {
+	f := Text(field, id)
+	if f.IsZero() {
+		return "", fmt.Errorf("redis4rag: empty %s", field)
+	}
+	return f.String(), nil
+}
+
 func (history *ChatHistory) delete(ctx context.Context, field string, value string) error {
 	var keypattern string
 	if field == ChatMessageSessionId.As {
-		keypattern = fmt.Sprintf("%s:*:%s:*", history.docPrefix, value)
+		keypattern = fmt.Sprintf("%s:*:%s:*", escapeGlob(history.docPrefix), escapeGlob(value))
 	} else if field == ChatMessageUserId.As {
-		keypattern = fmt.Sprintf("%s:%s:*", history.docPrefix, value)
+		keypattern = fmt.Sprintf("%s:%s:*", escapeGlob(history.docPrefix), escapeGlob(hashTag(value)))
 	} else {
 		panic("unkown field name")
 	}
-	keys, err := history.redisCli.Keys(ctx, keypattern).Result()
-	if err != nil {
+	return scanKeys(ctx, history.redisCli, keypattern, func(keys []string) error {
+		_, err := delKeys(ctx, history.redisCli, keys)
 		return err
-	}
-	if len(keys) > 0 {
-		return history.redisCli.Del(ctx, keys...).Err()
-	} else {
-		return nil
-	}
+	})
+}
+
+// key pattern: {ChatHistory.DocPrefix}:{{Message.UserId}}:{Message.SessionId}:{Message.Timestamp},
+// hash tagged on the user so that a user's messages share one slot
+func (history *ChatHistory) key(msg *ChatMessage) string {
+	return fmt.Sprintf("%s:%s:%s:%d", history.docPrefix, hashTag(msg.UserId), msg.SessionId, msg.Timestamp)
+}
+
+// MigrateKeys moves the messages stored as {DocPrefix}:{Message.UserId}:... by versions before keys
+// were hash tagged to their current key, and returns how many it moved. Until it has run, DeleteByUserId
+// and DeleteBySessionId miss those messages; run it once after upgrading.
+func (history *ChatHistory) MigrateKeys(ctx context.Context) (int64, error) {
+	return migrateKeys(ctx, history.redisCli, history.docPrefix, func(value json.RawMessage) (string, bool) {
+		var msg ChatMessage
+		if json.Unmarshal(value, &msg) != nil || len(msg.UserId) == 0 {
+			return "", false
+		}
+		return history.key(&msg), true
+	})
 }
 
 func parseChatMessage(doc *redis.Document) *ChatMessage {
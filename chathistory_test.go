@@ -1,10 +1,8 @@
 package redis4rag
 
 import (
-	"cmp"
 	"context"
 	"encoding/json"
-	"os"
 	"testing"
 	"time"
 
@@ -12,26 +10,29 @@ import (
 	should "github.com/stretchr/testify/assert"
 )
 
+func TestIdClause(t *testing.T) {
+	clause, err := idClause(ChatMessageUserId.As, "u-1{x}")
+	should.Nil(t, err)
+	should.Equal(t, `@user_id:(u\-1\{x\})`, clause)
+	_, err = idClause(ChatMessageSessionId.As, " ")
+	should.NotNil(t, err)
+}
+
 func TestChatHistoryBasic(t *testing.T) {
 	indexname := "idx:test_chat_history_basic"
 	docprefix := "doc:test_chat_history_basic"
-	redisCli := redis.NewClient(&redis.Options{
-		Addr:     cmp.Or(os.Getenv("REDIS_URL"), "localhost:6379"),
-		Protocol: 2,
-	})
+	redisCli := testRedis(t)
 	redisCli.FTDropIndexWithArgs(context.Background(), indexname, &redis.FTDropIndexOptions{DeleteDocs: true})
 
-	err := CreateIndex(redisCli, ChatHistorySchema, indexname, []interface{}{docprefix})
+	chatHistory, err := NewChatHistory(context.Background(),
+		WithIndexName(indexname),
+		WithPrefix(docprefix),
+		WithRedisClient(redisCli),
+		WithEmbedder(localEmbedder.Embedding),
+		WithCreateIndex(true))
 	should.Nil(t, err)
 	t.Logf("index %s created", indexname)
 
-	//TODO
-	chatHistory := &ChatHistory{
-		indexName: indexname,
-		docPrefix: docprefix,
-		redisCli:  redisCli,
-	}
-
 	t1 := time.Now()
 	time.Sleep(1 * time.Millisecond)
 	err = chatHistory.Add(context.Background(), &ChatMessage{
@@ -88,6 +89,20 @@ func TestChatHistoryBasic(t *testing.T) {
 		should.Nil(t, err)
 		t.Logf("%s\n", string(buf))
 	}
+	{
+		msgs, err := chatHistory.SearchWithUserId(context.Background(),
+			t1.UnixMilli(), "user_id", "咱俩关系不错呀。", localEmbedder.Embedding,
+			WithFilter(And(Tag(ChatMessageType.As, "assistant"), Gte(ChatMessageTimestamp.As, float64(t1.UnixMilli())))))
+		should.Nil(t, err)
+		should.Empty(t, msgs)
+	}
+	{
+		msgs, err := chatHistory.SearchWithSessionId(context.Background(),
+			t1.UnixMilli(), "session_id_2", "咱俩关系不错呀。", localEmbedder.Embedding)
+		should.Nil(t, err)
+		should.Equal(t, 1, len(msgs))
+		should.Equal(t, "session_id_2", msgs[0].SessionId)
+	}
 
 	{
 		err := chatHistory.DeleteBySessionId(context.Background(), "session_id")
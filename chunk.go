@@ -0,0 +1,48 @@
+package redis4rag
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/bitsark/redis4rag/chunking"
+)
+
+// DocChunk and DocChunks are the JSON paths of Document.Chunk and Document.Chunks.
+const (
+	DocChunk  = "$.chunk"
+	DocChunks = "$.chunks"
+)
+
+// ChunkRef links a chunk document to the document it was split from.
+type ChunkRef struct {
+	ParentID string `json:"parent_id"`
+	Index    int    `json:"index"`
+	// Start and End are the byte offsets of the chunk in the parent content.
+	Start    int      `json:"start"`
+	End      int      `json:"end"`
+	Headings []string `json:"headings,omitempty"`
+}
+
+// SplitDocument splits the content of doc with splitter into chunk documents with IDs
+// "{doc.ID}#{index}", each inheriting the tag, payload and metadata of doc.
+func SplitDocument(doc *Document, splitter chunking.Splitter) []*Document {
+	chunks := splitter.Split(doc.Content)
+	docs := make([]*Document, len(chunks))
+	for i, chunk := range chunks {
+		docs[i] = &Document{
+			ID:       fmt.Sprintf("%s#%d", doc.ID, chunk.Index),
+			Tag:      doc.Tag,
+			Content:  chunk.Text,
+			Payload:  doc.Payload,
+			Metadata: maps.Clone(doc.Metadata),
+			Chunk: &ChunkRef{
+				ParentID: doc.ID,
+				Index:    chunk.Index,
+				Start:    chunk.Start,
+				End:      chunk.End,
+				Headings: chunk.Headings,
+			},
+		}
+	}
+	return docs
+}
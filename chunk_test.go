@@ -0,0 +1,23 @@
+package redis4rag
+
+import (
+	"testing"
+
+	"github.com/bitsark/redis4rag/chunking"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestSplitDocument(t *testing.T) {
+	doc := &Document{ID: "faq", Tag: "faq", Content: "咱俩谁跟谁呀。我俩谁跟谁呀！", Metadata: map[string]any{"year": 2024}}
+	chunks := SplitDocument(doc, &chunking.Sentence{Size: 7})
+	should.Equal(t, []*Document{
+		{ID: "faq#0", Tag: "faq", Content: "咱俩谁跟谁呀。", Metadata: map[string]any{"year": 2024},
+			Chunk: &ChunkRef{ParentID: "faq", Index: 0, Start: 0, End: 21}},
+		{ID: "faq#1", Tag: "faq", Content: "我俩谁跟谁呀！", Metadata: map[string]any{"year": 2024},
+			Chunk: &ChunkRef{ParentID: "faq", Index: 1, Start: 21, End: 42}},
+	}, chunks)
+
+	chunks[0].Metadata["year"] = 2025
+	should.Equal(t, 2024, doc.Metadata["year"])
+	should.Empty(t, SplitDocument(&Document{ID: "empty"}, &chunking.Sentence{Size: 7}))
+}
@@ -0,0 +1,105 @@
+// Package chunking splits long texts into chunks small enough to embed, keeping the byte offsets
+// of every chunk in the original text. Sizes are counted in characters (runes) unless a splitter
+// says otherwise, so that CJK text is measured the way models see it rather than in UTF-8 bytes.
+package chunking
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type (
+	// Splitter splits a text into chunks in reading order.
+	Splitter interface {
+		Split(text string) []Chunk
+	}
+
+	// Chunk is a piece of a text, Text being text[Start:End].
+	Chunk struct {
+		Index int
+		Text  string
+		Start int
+		End   int
+		// Headings is the Markdown heading path of the chunk, outermost first.
+		Headings []string
+	}
+
+	// span is the half open byte range [start, end) of a piece of text, length long.
+	span struct {
+		start  int
+		end    int
+		length int
+	}
+)
+
+// DefaultSize is the Size of the splitters when theirs is <= 0, in the unit they measure it in.
+const DefaultSize = 512
+
+// RuneCount is the default length function of the splitters.
+func RuneCount(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// pack merges consecutive pieces into chunks of at most size, each starting with up to overlap
+// of the end of the previous one. A piece longer than size makes a chunk of its own.
+func pack(text string, pieces []span, size, overlap int, headings []string) (chunks []Chunk) {
+	var (
+		window []span
+		total  int
+	)
+	flush := func() {
+		if len(window) > 0 {
+			if chunk, ok := newChunk(text, window[0].start, window[len(window)-1].end, headings); ok {
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	for _, piece := range pieces {
+		if len(window) > 0 && total+piece.length > size {
+			flush()
+			for len(window) > 0 && (total > overlap || total+piece.length > size) {
+				total -= window[0].length
+				window = window[1:]
+			}
+		}
+		window = append(window, piece)
+		total += piece.length
+	}
+	flush()
+	return
+}
+
+// newChunk trims the whitespace around text[start:end], reporting false when nothing is left.
+func newChunk(text string, start, end int, headings []string) (Chunk, bool) {
+	s := text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	if len(trimmed) == 0 {
+		return Chunk{}, false
+	}
+	return Chunk{Text: trimmed, Start: start, End: start + len(trimmed), Headings: headings}, true
+}
+
+// numbered sets the Index of chunks in order.
+func numbered(chunks []Chunk) []Chunk {
+	for i := range chunks {
+		chunks[i].Index = i
+	}
+	return chunks
+}
+
+func sizeOf(size int) int {
+	if size <= 0 {
+		return DefaultSize
+	}
+	return size
+}
+
+func lengthOf(length func(string) int) func(string) int {
+	if length == nil {
+		return RuneCount
+	}
+	return length
+}
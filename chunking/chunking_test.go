@@ -0,0 +1,21 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSize(t *testing.T) {
+	// 700 characters, 600 tokens
+	text := strings.Repeat("咱俩谁跟谁呀。", 100)
+	for _, splitter := range []Splitter{&RecursiveCharacter{}, &Sentence{}, &Markdown{}, &Sentence{Size: -1}} {
+		chunks := splitter.Split(text)
+		should.Equal(t, 2, len(chunks))
+		should.Equal(t, 511, RuneCount(chunks[0].Text))
+	}
+	chunks := (&Token{}).Split(text)
+	should.Equal(t, 2, len(chunks))
+	should.Equal(t, DefaultSize, len(Words(chunks[0].Text)))
+}
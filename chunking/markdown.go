@@ -0,0 +1,113 @@
+package chunking
+
+import (
+	"strings"
+)
+
+// Markdown splits a Markdown text into its sections, starting at every ATX heading (# to ######)
+// outside fenced code blocks, and records the heading path of each chunk. Sections longer than
+// Size are split further by a RecursiveCharacter with the same settings; sections are never merged,
+// and the ones holding nothing but their heading are dropped.
+type Markdown struct {
+	// Size defaults to DefaultSize.
+	Size    int
+	Overlap int
+	// Length measures Size and Overlap, RuneCount by default.
+	Length func(string) int
+}
+
+type section struct {
+	start    int
+	end      int
+	body     int // the offset after the heading line
+	headings []string
+}
+
+func (s *Markdown) Split(text string) []Chunk {
+	length, size := lengthOf(s.Length), sizeOf(s.Size)
+	fallback := &RecursiveCharacter{Size: size, Overlap: s.Overlap, Length: s.Length}
+	var chunks []Chunk
+	for _, sec := range sections(text) {
+		if len(strings.TrimSpace(text[sec.body:sec.end])) == 0 {
+			continue
+		}
+		if length(text[sec.start:sec.end]) <= size {
+			if chunk, ok := newChunk(text, sec.start, sec.end, sec.headings); ok {
+				chunks = append(chunks, chunk)
+			}
+			continue
+		}
+		chunks = append(chunks, pack(text, fallback.pieces(text, sec.start, sec.end), size, s.Overlap, sec.headings)...)
+	}
+	return numbered(chunks)
+}
+
+// sections cuts text before every heading line.
+func sections(text string) (secs []section) {
+	var (
+		path  []string
+		fence string
+		cur   = section{}
+	)
+	for start := 0; start < len(text); {
+		end := len(text)
+		if i := strings.IndexByte(text[start:], '\n'); i >= 0 {
+			end = start + i + 1
+		}
+		line := strings.TrimRight(text[start:end], "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case len(fence) > 0:
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"), strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if level, title, ok := heading(line); ok {
+				if start > cur.start {
+					cur.end = start
+					secs = append(secs, cur)
+				}
+				if level > len(path) {
+					path = append(path, make([]string, level-len(path))...)
+				}
+				path = append(path[:level-1], title)
+				cur = section{start: start, body: end, headings: compact(path)}
+			}
+		}
+		start = end
+	}
+	if cur.start < len(text) {
+		cur.end = len(text)
+		secs = append(secs, cur)
+	}
+	return
+}
+
+// heading parses an ATX heading line such as "## Title ##".
+func heading(line string) (level int, title string, ok bool) {
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 {
+		return
+	}
+	line = strings.TrimLeft(line, " ")
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, "", false
+	}
+	title = strings.TrimSpace(line[level:])
+	title = strings.TrimSpace(strings.TrimRight(title, "#"))
+	return level, title, true
+}
+
+// compact copies path without the levels skipped by the headings.
+func compact(path []string) (headings []string) {
+	for _, title := range path {
+		if len(title) > 0 {
+			headings = append(headings, title)
+		}
+	}
+	return
+}
@@ -0,0 +1,37 @@
+package chunking
+
+import (
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	text := "Intro\n\n# Guide\n\n## Install\n\nRun it.\n\n```sh\n# not a heading\n```\n\n#### Deep ####\n\nDeep text.\n\n# Empty\n\n# FAQ\n咱俩谁跟谁呀。我俩谁跟谁呀！"
+	chunks := (&Markdown{Size: 30}).Split(text)
+	should.Equal(t, []string{
+		"Intro",
+		"## Install\n\nRun it.",
+		"```sh\n# not a heading\n```",
+		"#### Deep ####\n\nDeep text.",
+		"# FAQ\n咱俩谁跟谁呀。我俩谁跟谁呀！",
+	}, texts(chunks))
+	should.Nil(t, chunks[0].Headings)
+	should.Equal(t, []string{"Guide", "Install"}, chunks[1].Headings)
+	should.Equal(t, []string{"Guide", "Install"}, chunks[2].Headings)
+	should.Equal(t, []string{"Guide", "Install", "Deep"}, chunks[3].Headings)
+	should.Equal(t, []string{"FAQ"}, chunks[4].Headings)
+	for i, chunk := range chunks {
+		should.Equal(t, i, chunk.Index)
+		should.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+	}
+
+	level, title, ok := heading("  ## Title ##")
+	should.True(t, ok)
+	should.Equal(t, 2, level)
+	should.Equal(t, "Title", title)
+	_, _, ok = heading("#hashtag")
+	should.False(t, ok)
+	_, _, ok = heading("####### seven")
+	should.False(t, ok)
+}
@@ -0,0 +1,78 @@
+package chunking
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSeparators go from paragraphs down to single characters, the CJK sentence and clause
+// punctuation ranking next to their ASCII counterparts.
+var DefaultSeparators = []string{
+	"\n\n", "\n",
+	"。", "！", "？", ". ", "! ", "? ",
+	"；", "; ",
+	"，", "、", ", ",
+	" ",
+	"",
+}
+
+// RecursiveCharacter splits on the first of Separators found in the text, splitting the pieces
+// still longer than Size on the next ones, then merges neighbouring pieces back into chunks of up
+// to Size. Separators stay at the end of the piece they close, and "" splits between characters.
+type RecursiveCharacter struct {
+	// Size defaults to DefaultSize.
+	Size    int
+	Overlap int
+	// Separators default to DefaultSeparators.
+	Separators []string
+	// Length measures Size and Overlap, RuneCount by default.
+	Length func(string) int
+}
+
+func (s *RecursiveCharacter) Split(text string) []Chunk {
+	return numbered(pack(text, s.pieces(text, 0, len(text)), sizeOf(s.Size), s.Overlap, nil))
+}
+
+// pieces splits text[start:end] into spans no longer than Size, unless no separator can cut them.
+func (s *RecursiveCharacter) pieces(text string, start, end int) []span {
+	separators := s.Separators
+	if separators == nil {
+		separators = DefaultSeparators
+	}
+	return s.split(text, start, end, separators)
+}
+
+func (s *RecursiveCharacter) split(text string, start, end int, separators []string) (pieces []span) {
+	length := lengthOf(s.Length)
+	if n := length(text[start:end]); n <= sizeOf(s.Size) {
+		return []span{{start, end, n}}
+	}
+	for i, sep := range separators {
+		parts := cut(text, start, end, sep)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, part := range parts {
+			pieces = append(pieces, s.split(text, part.start, part.end, separators[i+1:])...)
+		}
+		return
+	}
+	return []span{{start, end, length(text[start:end])}}
+}
+
+// cut splits text[start:end] after every sep, or between characters when sep is "".
+func cut(text string, start, end int, sep string) (parts []span) {
+	for start < end {
+		var n int
+		if len(sep) == 0 {
+			_, n = utf8.DecodeRuneInString(text[start:end])
+		} else if i := strings.Index(text[start:end], sep); i >= 0 {
+			n = i + len(sep)
+		} else {
+			n = end - start
+		}
+		parts = append(parts, span{start: start, end: start + n})
+		start += n
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestRecursiveCharacter(t *testing.T) {
+	text := "咱俩谁跟谁呀。我俩谁跟谁呀！今天天气很好？\n\nRedis 7.2 is out. It supports vectors."
+	chunks := (&RecursiveCharacter{Size: 14, Overlap: 7}).Split(text)
+	// "今天天气很好？\n\n" and the overlap would not fit together
+	should.Equal(t, []string{
+		"咱俩谁跟谁呀。我俩谁跟谁呀！",
+		"今天天气很好？",
+		"Redis 7.2 is",
+		"7.2 is out.",
+		"out. It",
+		"It supports",
+		"vectors.",
+	}, texts(chunks))
+	for i, chunk := range chunks {
+		should.Equal(t, i, chunk.Index)
+		should.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+		should.LessOrEqual(t, RuneCount(chunk.Text), 14)
+	}
+
+	// without separators left, pieces are cut between characters
+	chunks = (&RecursiveCharacter{Size: 4, Separators: []string{""}}).Split("abcdefghij")
+	should.Equal(t, []string{"abcd", "efgh", "ij"}, texts(chunks))
+
+	// Length counts bytes instead of runes
+	chunks = (&RecursiveCharacter{Size: 6, Length: func(s string) int { return len(s) }}).Split("咱俩谁跟")
+	should.Equal(t, []string{"咱俩", "谁跟"}, texts(chunks))
+
+	should.Empty(t, (&RecursiveCharacter{Size: 10}).Split(" \n\n "))
+}
+
+func texts(chunks []Chunk) []string {
+	var s []string
+	for _, chunk := range chunks {
+		s = append(s, strings.TrimSpace(chunk.Text))
+	}
+	return s
+}
@@ -0,0 +1,71 @@
+package chunking
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sentence packs whole sentences into chunks of up to Size, overlapping by the last sentences
+// of the previous chunk totalling at most Overlap. Sentences end with 。！？ and their ASCII
+// counterparts, followed by any closing quotes or brackets; a '.' only ends one before a space or
+// the end of the text, so that numbers such as 3.14 stay whole. Blank lines end sentences too.
+// Sentences longer than Size are split by a RecursiveCharacter with the same settings.
+type Sentence struct {
+	// Size defaults to DefaultSize.
+	Size    int
+	Overlap int
+	// Length measures Size and Overlap, RuneCount by default.
+	Length func(string) int
+}
+
+func (s *Sentence) Split(text string) []Chunk {
+	length, size := lengthOf(s.Length), sizeOf(s.Size)
+	fallback := &RecursiveCharacter{Size: size, Length: s.Length}
+	var pieces []span
+	for _, sentence := range sentences(text) {
+		if n := length(text[sentence.start:sentence.end]); n <= size {
+			pieces = append(pieces, span{sentence.start, sentence.end, n})
+		} else {
+			pieces = append(pieces, fallback.pieces(text, sentence.start, sentence.end)...)
+		}
+	}
+	return numbered(pack(text, pieces, size, s.Overlap, nil))
+}
+
+// sentences splits text at sentence ends, trailing whitespace included.
+func sentences(text string) (spans []span) {
+	start := 0
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		end := -1
+		switch {
+		case strings.ContainsRune("。！？!?…", r):
+			end = i + n
+		case r == '.':
+			if next, _ := utf8.DecodeRuneInString(text[i+n:]); i+n == len(text) || unicode.IsSpace(next) {
+				end = i + n
+			}
+		case r == '\n' && strings.HasPrefix(text[i+n:], "\n"):
+			end = i + n
+		}
+		i += n
+		if end < 0 {
+			continue
+		}
+		// closing punctuation and the whitespace after belong to the sentence
+		for end < len(text) {
+			r, n := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune("。！？!?…\"'”’」』）)]】", r) && !unicode.IsSpace(r) {
+				break
+			}
+			end += n
+		}
+		spans = append(spans, span{start: start, end: end})
+		start, i = end, end
+	}
+	if start < len(text) {
+		spans = append(spans, span{start: start, end: len(text)})
+	}
+	return
+}
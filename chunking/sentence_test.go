@@ -0,0 +1,28 @@
+package chunking
+
+import (
+	"strings"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestSentence(t *testing.T) {
+	text := "他说：“咱俩谁跟谁呀！”我笑了。Pi is 3.14. Really?\n\nYes"
+	var got []string
+	for _, sentence := range sentences(text) {
+		got = append(got, strings.TrimSpace(text[sentence.start:sentence.end]))
+	}
+	should.Equal(t, []string{"他说：“咱俩谁跟谁呀！”", "我笑了。", "Pi is 3.14.", "Really?", "Yes"}, got)
+
+	chunks := (&Sentence{Size: 16, Overlap: 4}).Split(text)
+	// "Pi is 3.14. " is longer than Overlap, so "Really?" starts afresh
+	should.Equal(t, []string{"他说：“咱俩谁跟谁呀！”我笑了。", "我笑了。Pi is 3.14.", "Really?\n\nYes"}, texts(chunks))
+	for _, chunk := range chunks {
+		should.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+	}
+
+	// a sentence longer than Size falls back to clauses
+	chunks = (&Sentence{Size: 6}).Split("一二三，四五六，七八九。")
+	should.Equal(t, []string{"一二三，", "四五六，", "七八九。"}, texts(chunks))
+}
@@ -0,0 +1,64 @@
+package chunking
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token splits text into windows of Size tokens, consecutive windows sharing Overlap tokens.
+// Tokens default to runs of letters or digits and single CJK characters, a rough stand-in for the
+// tokenizer of the embedding model; set Tokenize to count with the real one.
+type Token struct {
+	// Size defaults to DefaultSize.
+	Size    int
+	Overlap int
+	// Tokenize returns the [start, end) byte offsets of the tokens of text, in order.
+	Tokenize func(text string) [][2]int
+}
+
+func (s *Token) Split(text string) []Chunk {
+	tokenize := s.Tokenize
+	if tokenize == nil {
+		tokenize = Words
+	}
+	tokens := tokenize(text)
+	// every token piece runs up to the next token, so that chunks keep the text in between
+	pieces := make([]span, len(tokens))
+	for i, token := range tokens {
+		end := len(text)
+		if i+1 < len(tokens) {
+			end = tokens[i+1][0]
+		}
+		pieces[i] = span{start: token[0], end: end, length: 1}
+	}
+	if len(pieces) > 0 {
+		pieces[0].start = 0
+	}
+	return numbered(pack(text, pieces, sizeOf(s.Size), s.Overlap, nil))
+}
+
+// Words tokenizes text into runs of letters or digits and single CJK characters.
+func Words(text string) (tokens [][2]int) {
+	start := -1
+	for i, r := range text {
+		cjk := isCJK(r)
+		if start >= 0 && (cjk || !unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			tokens = append(tokens, [2]int{start, i})
+			start = -1
+		}
+		switch {
+		case cjk:
+			tokens = append(tokens, [2]int{i, i + utf8.RuneLen(r)})
+		case start < 0 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, [2]int{start, len(text)})
+	}
+	return
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,29 @@
+package chunking
+
+import (
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestToken(t *testing.T) {
+	should.Equal(t, [][2]int{{0, 5}, {6, 9}, {9, 12}, {13, 14}, {15, 16}}, Words("Redis 咱俩 7.2"))
+
+	text := "Redis 咱俩谁跟谁呀。 vector search"
+	chunks := (&Token{Size: 4, Overlap: 1}).Split(text)
+	should.Equal(t, []string{"Redis 咱俩谁", "谁跟谁呀。", "呀。 vector search"}, texts(chunks))
+	for _, chunk := range chunks {
+		should.Equal(t, chunk.Text, text[chunk.Start:chunk.End])
+	}
+
+	whitespace := func(text string) (tokens [][2]int) {
+		for i, r := range text + " " {
+			if r != ' ' && (i == 0 || text[i-1] == ' ') {
+				tokens = append(tokens, [2]int{i, i})
+			}
+		}
+		return
+	}
+	chunks = (&Token{Size: 2, Tokenize: whitespace}).Split("a b c")
+	should.Equal(t, []string{"a b", "c"}, texts(chunks))
+}
@@ -0,0 +1,216 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoFilter is returned by DeleteByFilter for a zero Filter, which would delete every document.
+var ErrNoFilter = errors.New("redis4rag: delete by filter needs a filter")
+
+// Get reads the documents of ids, nil for the missing ones, without their vectors.
+func (r *Retriever) Get(ctx context.Context, ids ...string) ([]*Document, error) {
+	return r.getDocuments(ctx, ids)
+}
+
+// Exists reports whether the document id is stored.
+func (r *Retriever) Exists(ctx context.Context, id string) (bool, error) {
+	n, err := r.redisCli.Exists(ctx, r.key(id)).Result()
+	return n > 0, err
+}
+
+// Delete deletes the documents of ids, along with the chunks of those stored by StoreChunked,
+// and returns how many of ids existed.
+func (r *Retriever) Delete(ctx context.Context, ids ...string) (deleted int64, err error) {
+	docs, err := r.getDocuments(ctx, ids)
+	if err != nil {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.key(id)
+	}
+	var chunks []string
+	for _, doc := range docs {
+		if doc != nil {
+			for _, id := range doc.Chunks {
+				chunks = append(chunks, r.key(id))
+			}
+		}
+	}
+	if deleted, err = delKeys(ctx, r.redisCli, keys); err != nil || len(chunks) == 0 {
+		return
+	}
+	_, err = delKeys(ctx, r.redisCli, chunks)
+	return
+}
+
+// DeleteByFilter deletes the documents matching filter, page by page, along with the chunks of those
+// stored by StoreChunked, and returns how many matching documents it deleted. Documents indexed while
+// it runs are deleted too when they match.
+func (r *Retriever) DeleteByFilter(ctx context.Context, filter Filter) (deleted int64, err error) {
+	if filter.IsZero() {
+		return 0, ErrNoFilter
+	}
+	opts := &redis.FTSearchOptions{
+		Return:         []redis.FTSearchReturn{{FieldName: DocChunks}},
+		DialectVersion: 2,
+		Limit:          rangePageSize,
+	}
+	for {
+		res, err := r.redisCli.FTSearchWithArgs(ctx, r.indexName, filter.String(), opts).Result()
+		if err != nil || len(res.Docs) == 0 {
+			return deleted, err
+		}
+		var keys, chunks []string
+		for _, doc := range res.Docs {
+			keys = append(keys, doc.ID)
+			for _, id := range parseChunks(doc.Fields[DocChunks]) {
+				chunks = append(chunks, r.key(id))
+			}
+		}
+		n, err := delKeys(ctx, r.redisCli, keys)
+		deleted += n
+		if err == nil && len(chunks) > 0 {
+			_, err = delKeys(ctx, r.redisCli, chunks)
+		}
+		// deleted documents leave the index, so the next page starts at 0 again;
+		// stop when nothing could be deleted rather than finding the same keys forever
+		if err != nil || n == 0 {
+			return deleted, err
+		}
+	}
+}
+
+// Update replaces the stored doc, re-embedding its content vector only when Content changed and
+// each named vector only when its text did, and keeps the other vectors as they are. The document
+// is written in a single command, so searches never see it half updated. A document not stored
+// yet is stored as by Store. A parent stored by StoreChunked keeps its chunks and stays without
+// vectors; call StoreChunked again to split a changed Content.
+func (r *Retriever) Update(ctx context.Context, doc *Document, embedder Embedder) (err error) {
+	raw, err := r.redisCli.JSONGet(ctx, r.key(doc.ID), "$").Result()
+	if err != nil && err != redis.Nil {
+		return
+	}
+	var matches []json.RawMessage
+	if len(raw) > 0 {
+		if err = json.Unmarshal([]byte(raw), &matches); err != nil {
+			return
+		}
+	}
+	if len(matches) == 0 {
+		return r.Store(ctx, doc, embedder)
+	}
+	var (
+		old    Document
+		stored map[string]json.RawMessage
+		fields map[string]json.RawMessage
+	)
+	if err = json.Unmarshal(matches[0], &old); err != nil {
+		return
+	}
+	if err = json.Unmarshal(matches[0], &stored); err != nil {
+		return
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+	if fields["terms"], err = json.Marshal(lexicalText(doc.Content)); err != nil {
+		return
+	}
+
+	parent := len(old.Chunks) > 0
+	vecField := strings.TrimPrefix(DocContentVec.FieldName, "$.")
+	switch {
+	case parent:
+		for _, path := range []string{DocChunks, DocRole.FieldName} {
+			field := strings.TrimPrefix(path, "$.")
+			if value, ok := stored[field]; ok {
+				fields[field] = value
+			}
+		}
+	case doc.Content == old.Content && len(stored[vecField]) > 0:
+		fields[vecField] = stored[vecField]
+		if embedding, ok := stored["embedding"]; ok {
+			fields["embedding"] = embedding
+		}
+	default:
+		var vec []float64
+		if vec, err = embed(ctx, doc.Content, embedder, r.embedder); err != nil {
+			return
+		}
+		if vec, err = r.vector.prepare(vec); err != nil {
+			return
+		}
+		if fields[vecField], err = json.Marshal(r.vector.jsonValue(vec)); err != nil {
+			return
+		}
+		if len(r.model.ID) > 0 {
+			if fields["embedding"], err = json.Marshal(r.model); err != nil {
+				return
+			}
+		}
+	}
+
+	if len(r.named) > 0 && !parent {
+		var named map[string]json.RawMessage
+		if len(stored["vectors"]) > 0 {
+			if err = json.Unmarshal(stored["vectors"], &named); err != nil {
+				return
+			}
+		}
+		var (
+			changed  []NamedVector
+			fallback = cmpEmbedder(embedder, r.embedder)
+		)
+		for _, v := range r.named {
+			if _, ok := named[v.Name]; !ok || v.text(doc) != v.text(&old) {
+				if v.Embedder == nil && fallback == nil {
+					return ErrNoEmbedder
+				}
+				changed = append(changed, v)
+			}
+		}
+		var vecs []map[string]interface{}
+		if vecs, err = r.embedVectors(ctx, changed, []*Document{doc}, fallback.Batch()); err != nil {
+			return
+		}
+		if named == nil {
+			named = make(map[string]json.RawMessage, len(r.named))
+		}
+		for name, vec := range vecs[0] {
+			if named[name], err = json.Marshal(vec); err != nil {
+				return
+			}
+		}
+		if fields["vectors"], err = json.Marshal(named); err != nil {
+			return
+		}
+	}
+
+	if data, err = json.Marshal(fields); err != nil {
+		return
+	}
+	return r.redisCli.JSONSet(ctx, r.key(doc.ID), "$", string(data)).Err()
+}
+
+// parseChunks decodes the Document.Chunks returned by a search, possibly wrapped in a JSONPath match array.
+func parseChunks(raw string) []string {
+	var ids []string
+	if json.Unmarshal([]byte(raw), &ids) == nil {
+		return ids
+	}
+	var matches [][]string
+	if json.Unmarshal([]byte(raw), &matches) == nil && len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
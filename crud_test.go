@@ -0,0 +1,168 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitsark/redis4rag/chunking"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestRetrieverCRUD(t *testing.T) {
+	ctx := context.Background()
+
+	var embedded, titles []string
+	hash := NewHashEmbedder(128)
+	retriever := newTestRetriever(t, "retriever_crud",
+		WithEmbedder(func(ctx context.Context, text string) ([]float64, error) {
+			embedded = append(embedded, text)
+			return hash.Embed(ctx, text)
+		}),
+		WithVectorConfig(VectorConfig{Dim: 128}),
+		WithNamedVector(NamedVector{
+			Name:   "title",
+			Config: VectorConfig{Dim: 128, Type: VectorTypeFloat32},
+			Embedder: func(ctx context.Context, text string) ([]float64, error) {
+				titles = append(titles, text)
+				return hash.Embed(ctx, text)
+			},
+			Text: func(doc *Document) string { return doc.Payload },
+		}),
+		WithMetadataField(MetadataField{Name: "lang", Type: MetadataTag}))
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "How do I reset my password?", Payload: "Passwords", Metadata: map[string]any{"lang": "en"}},
+		{Tag: "faq", ID: "1", Content: "Where is my invoice?", Payload: "Billing", Metadata: map[string]any{"lang": "en"}},
+		{Tag: "faq", ID: "2", Content: "如何重置密码？", Payload: "密码", Metadata: map[string]any{"lang": "zh"}},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Get(ctx, "0", "missing", "2")
+	should.Nil(t, err)
+	should.Equal(t, 3, len(docs))
+	should.Equal(t, "How do I reset my password?", docs[0].Content)
+	should.Nil(t, docs[1])
+	should.Equal(t, "zh", docs[2].Metadata["lang"])
+
+	ok, err := retriever.Exists(ctx, "1")
+	should.Nil(t, err)
+	should.True(t, ok)
+	ok, err = retriever.Exists(ctx, "missing")
+	should.Nil(t, err)
+	should.False(t, ok)
+
+	// only the metadata changes, nothing is embedded again
+	embedded, titles = nil, nil
+	err = retriever.Update(ctx, &Document{Tag: "faq", ID: "0", Content: "How do I reset my password?", Payload: "Passwords", Metadata: map[string]any{"lang": "de"}}, nil)
+	should.Nil(t, err)
+	should.Empty(t, embedded)
+	should.Empty(t, titles)
+	time.Sleep(100 * time.Millisecond)
+	found, err := retriever.Retrieve(ctx, "reset my password", "faq", 1, nil, WithFilter(Tag("lang", "de")))
+	should.Nil(t, err)
+	should.Equal(t, 1, len(found))
+	should.Equal(t, "0", found[0].ID)
+	found, err = retriever.Retrieve(ctx, "Passwords", "faq", 1, nil, WithVectorField("title"))
+	should.Nil(t, err)
+	should.Equal(t, "0", found[0].ID)
+
+	// the content changes, the title does not
+	embedded, titles = nil, nil
+	err = retriever.Update(ctx, &Document{Tag: "faq", ID: "1", Content: "Download past invoices", Payload: "Billing"}, nil)
+	should.Nil(t, err)
+	should.Equal(t, []string{"Download past invoices"}, embedded)
+	should.Empty(t, titles)
+	time.Sleep(100 * time.Millisecond)
+	found, err = retriever.Retrieve(ctx, "download invoices", "faq", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, "1", found[0].ID)
+	docs, err = retriever.Get(ctx, "1")
+	should.Nil(t, err)
+	should.Nil(t, docs[0].Metadata)
+
+	// updating a missing document stores it
+	embedded, titles = nil, nil
+	err = retriever.Update(ctx, &Document{Tag: "faq", ID: "3", Content: "Change the account email", Payload: "Account"}, nil)
+	should.Nil(t, err)
+	should.Equal(t, []string{"Change the account email"}, embedded)
+	should.Equal(t, []string{"Account"}, titles)
+
+	deleted, err := retriever.Delete(ctx, "3", "missing")
+	should.Nil(t, err)
+	should.Equal(t, int64(1), deleted)
+	ok, err = retriever.Exists(ctx, "3")
+	should.Nil(t, err)
+	should.False(t, ok)
+
+	_, err = retriever.DeleteByFilter(ctx, Filter{})
+	should.ErrorIs(t, err, ErrNoFilter)
+	time.Sleep(100 * time.Millisecond)
+	deleted, err = retriever.DeleteByFilter(ctx, Tag("lang", "zh"))
+	should.Nil(t, err)
+	should.Equal(t, int64(1), deleted)
+	docs, err = retriever.Get(ctx, "0", "1", "2")
+	should.Nil(t, err)
+	should.NotNil(t, docs[0])
+	should.NotNil(t, docs[1])
+	should.Nil(t, docs[2])
+}
+
+func TestRetrieverCRUDChunked(t *testing.T) {
+	ctx := context.Background()
+
+	title := NamedVector{
+		Name:   "title",
+		Config: VectorConfig{Dim: 64, Type: VectorTypeFloat32},
+		Text:   func(doc *Document) string { return doc.Payload },
+	}
+	hash := NewHashEmbedder(64)
+	retriever := newTestRetriever(t, "retriever_crud_chunked",
+		WithEmbedder(nil),
+		WithVectorConfig(VectorConfig{Dim: 64}),
+		WithNamedVector(title))
+
+	// a named vector without an embedder of its own fails instead of panicking
+	should.Nil(t, retriever.Store(ctx, &Document{ID: "0", Content: "reset password", Payload: "Passwords"}, hash.Embed))
+	err := retriever.Update(ctx, &Document{ID: "0", Content: "reset password", Payload: "Accounts"}, nil)
+	should.ErrorIs(t, err, ErrNoEmbedder)
+	should.Nil(t, retriever.Update(ctx, &Document{ID: "0", Content: "reset password", Payload: "Accounts"}, hash.Embed))
+
+	splitter := &chunking.Sentence{Size: 10}
+	faq := &Document{ID: "faq", Content: "如何重置我的密码？怎样修改收货地址？", Payload: "FAQ"}
+	should.Nil(t, retriever.StoreChunked(ctx, faq, splitter, hash.EmbedBatch))
+	time.Sleep(100 * time.Millisecond)
+
+	// updating a chunked parent keeps its chunks and leaves it without vectors
+	should.Nil(t, retriever.Update(ctx, &Document{ID: "faq", Content: faq.Content, Payload: "Help"}, hash.Embed))
+	docs, err := retriever.Get(ctx, "faq")
+	should.Nil(t, err)
+	should.Equal(t, "Help", docs[0].Payload)
+	should.Equal(t, []string{"faq#0", "faq#1"}, docs[0].Chunks)
+	vec, err := retriever.redisCli.JSONGet(ctx, retriever.key("faq"), DocContentVec.FieldName).Result()
+	should.Nil(t, err)
+	should.Equal(t, "[]", vec)
+	time.Sleep(100 * time.Millisecond)
+	found, err := retriever.Retrieve(ctx, "如何重置我的密码？", "", 5, hash.Embed, WithParentDocument())
+	should.Nil(t, err)
+	should.Equal(t, "faq", found[0].ID)
+	should.Nil(t, found[0].Chunk)
+
+	// deleting parents by filter deletes their chunks
+	deleted, err := retriever.DeleteByFilter(ctx, Tag(DocRole.As, RoleParent))
+	should.Nil(t, err)
+	should.Equal(t, int64(1), deleted)
+	n, err := retriever.redisCli.Exists(ctx, retriever.key("faq"), retriever.key("faq#0"), retriever.key("faq#1")).Result()
+	should.Nil(t, err)
+	should.Equal(t, int64(0), n)
+	ok, err := retriever.Exists(ctx, "0")
+	should.Nil(t, err)
+	should.True(t, ok)
+}
+
+func TestParseChunks(t *testing.T) {
+	should.Equal(t, []string{"a#0", "a#1"}, parseChunks(`["a#0","a#1"]`))
+	should.Equal(t, []string{"a#0"}, parseChunks(`[["a#0"]]`))
+	should.Nil(t, parseChunks(""))
+}
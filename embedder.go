@@ -1,5 +1,89 @@
 package redis4rag
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
-type Embedder func(context.Context, string) ([]float64, error)
+const DefaultBatchSize = 64
+
+var ErrNoEmbedder = errors.New("redis4rag: embedder is required")
+
+type (
+	Embedder func(context.Context, string) ([]float64, error)
+
+	// BatchEmbedder embeds several texts at once, returning one vector per text in order.
+	BatchEmbedder func(context.Context, []string) ([][]float64, error)
+)
+
+// Batch adapts embedder into a BatchEmbedder that calls it once per text.
+func (embedder Embedder) Batch() BatchEmbedder {
+	return func(ctx context.Context, texts []string) (vecs [][]float64, err error) {
+		vecs = make([][]float64, len(texts))
+		for i, text := range texts {
+			if vecs[i], err = embedder(ctx, text); err != nil {
+				return nil, err
+			}
+		}
+		return
+	}
+}
+
+// embed calls embedder, falling back to the handle's default one when it is nil.
+func embed(ctx context.Context, text string, embedder, fallback Embedder) ([]float64, error) {
+	if embedder == nil {
+		embedder = fallback
+	}
+	if embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	return embedder(ctx, text)
+}
+
+// cmpEmbedder returns the first non nil embedder.
+func cmpEmbedder(embedders ...Embedder) Embedder {
+	for _, embedder := range embedders {
+		if embedder != nil {
+			return embedder
+		}
+	}
+	return nil
+}
+
+// batchOf picks the first usable batch embedder, adapting the handle's single-text one as a last resort.
+func batchOf(embedder, fallback BatchEmbedder, single Embedder) BatchEmbedder {
+	switch {
+	case embedder != nil:
+		return embedder
+	case fallback != nil:
+		return fallback
+	case single != nil:
+		return single.Batch()
+	}
+	return nil
+}
+
+// forEachBatch embeds texts size at a time and hands every chunk [lo, hi) with its vectors to fn.
+func forEachBatch(ctx context.Context, texts []string, size int, embedder BatchEmbedder, fn func(lo, hi int, vecs [][]float64) error) error {
+	if embedder == nil {
+		return ErrNoEmbedder
+	}
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	for lo := 0; lo < len(texts); lo += size {
+		hi := min(lo+size, len(texts))
+		vecs, err := embedder(ctx, texts[lo:hi])
+		if err != nil {
+			return err
+		}
+		if len(vecs) != hi-lo {
+			return fmt.Errorf("redis4rag: batch embedder returned %d vectors for %d texts", len(vecs), hi-lo)
+		}
+		if err = fn(lo, hi, vecs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package redis4rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestBatchEmbedder(t *testing.T) {
+	ctx := context.Background()
+	var single Embedder = func(_ context.Context, text string) ([]float64, error) {
+		return []float64{float64(len(text))}, nil
+	}
+	vecs, err := single.Batch()(ctx, []string{"a", "bb", "ccc"})
+	should.Nil(t, err)
+	should.Equal(t, [][]float64{{1}, {2}, {3}}, vecs)
+
+	should.ErrorIs(t, forEachBatch(ctx, []string{"a"}, 2, batchOf(nil, nil, nil), nil), ErrNoEmbedder)
+
+	var calls, chunks [][]float64
+	var batch BatchEmbedder = func(ctx context.Context, texts []string) ([][]float64, error) {
+		calls = append(calls, []float64{float64(len(texts))})
+		return single.Batch()(ctx, texts)
+	}
+	err = forEachBatch(ctx, []string{"a", "bb", "ccc", "dddd", "eeeee"}, 2, batchOf(nil, batch, single),
+		func(lo, hi int, vecs [][]float64) error {
+			should.Equal(t, hi-lo, len(vecs))
+			chunks = append(chunks, []float64{float64(lo), float64(hi)})
+			return nil
+		})
+	should.Nil(t, err)
+	should.Equal(t, [][]float64{{2}, {2}, {1}}, calls)
+	should.Equal(t, [][]float64{{0, 2}, {2, 4}, {4, 5}}, chunks)
+
+	var short BatchEmbedder = func(context.Context, []string) ([][]float64, error) { return nil, nil }
+	should.NotNil(t, forEachBatch(ctx, []string{"a"}, 0, short, nil))
+
+	failed := errors.New("failed")
+	var failing Embedder = func(context.Context, string) ([]float64, error) { return nil, failed }
+	_, err = failing.Batch()(ctx, []string{"a"})
+	should.ErrorIs(t, err, failed)
+}
@@ -0,0 +1,142 @@
+package redis4rag
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Filter is a typed search filter compiled to RediSearch dialect 2 query syntax, with every value
+// escaped. Fields are index attribute names such as "tag". The zero Filter matches everything.
+//
+//	And(Tag("tenant", "X"), Gte("year", 2023), Not(Tag("tag", "draft")))
+//
+// compiles to (@tenant:{X} @year:[2023 +inf] -@tag:{draft}).
+type Filter struct {
+	expr string
+}
+
+const (
+	GeoUnitMeters     = "m"
+	GeoUnitKilometers = "km"
+	GeoUnitMiles      = "mi"
+	GeoUnitFeet       = "ft"
+)
+
+// Tag matches documents whose TAG field holds any of values.
+func Tag(field string, values ...string) Filter {
+	if len(values) == 0 {
+		return Filter{}
+	}
+	return Filter{tagClause(escapeTag(field), values...)}
+}
+
+// Range matches NUMERIC field values in [lo, hi]; use math.Inf for an open end.
+func Range(field string, lo, hi float64) Filter {
+	return numeric(field, formatNumber(lo, false), formatNumber(hi, false))
+}
+
+// Eq matches a NUMERIC field equal to value.
+func Eq(field string, value float64) Filter {
+	return Range(field, value, value)
+}
+
+func Gt(field string, value float64) Filter {
+	return numeric(field, formatNumber(value, true), "+inf")
+}
+
+func Gte(field string, value float64) Filter {
+	return Range(field, value, math.Inf(1))
+}
+
+func Lt(field string, value float64) Filter {
+	return numeric(field, "-inf", formatNumber(value, true))
+}
+
+func Lte(field string, value float64) Filter {
+	return Range(field, math.Inf(-1), value)
+}
+
+// Text matches documents whose TEXT field contains every word of text.
+func Text(field string, text string) Filter {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return Filter{}
+	}
+	for i, word := range words {
+		words[i] = escapeTag(word)
+	}
+	return Filter{fmt.Sprintf("@%s:(%s)", escapeTag(field), strings.Join(words, " "))}
+}
+
+// Geo matches GEO field points within radius of lon, lat, radius being in unit, e.g. GeoUnitKilometers.
+func Geo(field string, lon, lat, radius float64, unit string) Filter {
+	return Filter{fmt.Sprintf("@%s:[%s %s %s %s]", escapeTag(field),
+		formatNumber(lon, false), formatNumber(lat, false), formatNumber(radius, false), unit)}
+}
+
+// Not negates f; Not of the zero Filter is the zero Filter.
+func Not(f Filter) Filter {
+	if f.IsZero() {
+		return f
+	}
+	return Filter{"-" + f.expr}
+}
+
+// And matches documents matching every filter, zero ones being ignored.
+func And(filters ...Filter) Filter {
+	return join(" ", filters)
+}
+
+// Or matches documents matching any filter, zero ones being ignored.
+func Or(filters ...Filter) Filter {
+	return join("|", filters)
+}
+
+func (f Filter) IsZero() bool {
+	return len(f.expr) == 0
+}
+
+// String returns the compiled query, "" for the zero Filter.
+func (f Filter) String() string {
+	return f.expr
+}
+
+// join parenthesizes compound filters, so that they nest as atoms.
+func join(sep string, filters []Filter) Filter {
+	exprs := make([]string, 0, len(filters))
+	for _, f := range filters {
+		if !f.IsZero() {
+			exprs = append(exprs, f.expr)
+		}
+	}
+	switch len(exprs) {
+	case 0:
+		return Filter{}
+	case 1:
+		return Filter{exprs[0]}
+	}
+	return Filter{"(" + strings.Join(exprs, sep) + ")"}
+}
+
+func numeric(field, lo, hi string) Filter {
+	return Filter{fmt.Sprintf("@%s:[%s %s]", escapeTag(field), lo, hi)}
+}
+
+// formatNumber renders v as a query number, prefixed by "(" when the bound is exclusive.
+func formatNumber(v float64, exclusive bool) string {
+	var s string
+	switch {
+	case math.IsInf(v, 1):
+		s = "+inf"
+	case math.IsInf(v, -1):
+		s = "-inf"
+	default:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if exclusive {
+		return "(" + s
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package redis4rag
+
+import (
+	"math"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	should.Equal(t, `(@tenant:{X} @year:[2023 +inf] -@tag:{draft})`,
+		And(Tag("tenant", "X"), Gte("year", 2023), Not(Tag("tag", "draft"))).String())
+	should.Equal(t, `@tag:{a|b\-c|d\ e}`, Tag("tag", "a", "b-c", "d e").String())
+	should.Equal(t, `@price:[(9.5 +inf]`, Gt("price", 9.5).String())
+	should.Equal(t, `@price:[-inf (-1]`, Lt("price", -1).String())
+	should.Equal(t, `@price:[-inf 10]`, Lte("price", 10).String())
+	should.Equal(t, `@year:[2024 2024]`, Eq("year", 2024).String())
+	should.Equal(t, `@year:[2020 2023]`, Range("year", 2020, 2023).String())
+	should.Equal(t, `@price:[-inf +inf]`, Range("price", math.Inf(-1), math.Inf(1)).String())
+	should.Equal(t, `@content:(redis\-stack 7\.2)`, Text("content", " redis-stack  7.2 ").String())
+	should.Equal(t, `@location:[116.4 39.9 5 km]`, Geo("location", 116.4, 39.9, 5, GeoUnitKilometers).String())
+
+	should.Equal(t, `(@tag:{a}|(@tag:{b} @year:[2023 +inf]))`,
+		Or(Tag("tag", "a"), And(Tag("tag", "b"), Gte("year", 2023))).String())
+	should.Equal(t, `-(@tag:{a}|@tag:{b})`, Not(Or(Tag("tag", "a"), Tag("tag", "b"))).String())
+
+	// zero filters vanish
+	should.True(t, Tag("tag").IsZero())
+	should.True(t, Text("content", " ").IsZero())
+	should.True(t, Not(Filter{}).IsZero())
+	should.True(t, And(Filter{}, Or()).IsZero())
+	should.Equal(t, `@tag:{a}`, And(Filter{}, Tag("tag", "a")).String())
+
+	so := newSearchOptions(WithFilter(Tag("tag", "a")), WithFilter(Not(Eq("year", 2020))))
+	should.Equal(t, `@user_id:u @tag:{a} -@year:[2020 2020]`, so.filter("@user_id:u"))
+	should.Equal(t, "*", newSearchOptions(WithFilter(Filter{})).filter())
+}
@@ -0,0 +1,135 @@
+package redis4rag
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashEmbedder is a dependency free, deterministic Embedder for development and tests.
+// It hashes character n-grams into a fixed number of buckets (the hashing trick):
+// CJK text contributes its character unigrams and bigrams, other scripts their lowercase
+// words and the character trigrams of each word. Vectors are L2 normalized, so texts
+// sharing more n-grams score closer under COSINE, IP and L2 alike.
+type HashEmbedder struct {
+	dim int
+}
+
+// NewHashEmbedder makes a HashEmbedder of dim dimensions, DefaultVectorConfig.Dim when dim <= 0.
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = DefaultVectorConfig.Dim
+	}
+	return &HashEmbedder{dim: dim}
+}
+
+// Embed is an Embedder.
+func (e *HashEmbedder) Embed(_ context.Context, text string) ([]float64, error) {
+	vec := make([]float64, e.dim)
+	for _, feature := range hashFeatures(text) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		// the top bit picks the sign so that colliding features tend to cancel out
+		if sum>>63 == 0 {
+			vec[sum%uint64(e.dim)] += 1
+		} else {
+			vec[sum%uint64(e.dim)] -= 1
+		}
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, nil
+}
+
+// EmbedBatch is a BatchEmbedder.
+func (e *HashEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	return Embedder(e.Embed).Batch()(ctx, texts)
+}
+
+func hashFeatures(text string) (features []string) {
+	for _, run := range segment(text) {
+		runes := []rune(run)
+		if isCJK(runes[0]) {
+			for i := range runes {
+				features = append(features, "u:"+string(runes[i]))
+				if i+1 < len(runes) {
+					features = append(features, "b:"+string(runes[i:i+2]))
+				}
+			}
+			continue
+		}
+		features = append(features, "w:"+run)
+		padded := []rune("<" + run + ">")
+		for i := 0; i+3 <= len(padded); i++ {
+			features = append(features, "t:"+string(padded[i:i+3]))
+		}
+	}
+	return
+}
+
+// segment splits text into lowercase runs of CJK characters and runs of letters or digits,
+// dropping whitespace and punctuation, including the fullwidth CJK one such as 。！？
+func segment(text string) (runs []string) {
+	var b strings.Builder
+	cjk := false
+	flush := func() {
+		if b.Len() > 0 {
+			runs = append(runs, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range text {
+		// fold fullwidth ASCII variants, common in CJK text, to their ASCII form
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+		r = unicode.ToLower(r)
+		switch {
+		case isCJK(r):
+			if !cjk {
+				flush()
+			}
+			cjk = true
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if cjk {
+				flush()
+			}
+			cjk = false
+			b.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return
+}
+
+// tokenize splits text into words and single CJK characters.
+func tokenize(text string) (tokens []string) {
+	for _, run := range segment(text) {
+		if runes := []rune(run); isCJK(runes[0]) {
+			for _, r := range runes {
+				tokens = append(tokens, string(r))
+			}
+		} else {
+			tokens = append(tokens, run)
+		}
+	}
+	return
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,48 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	embedder := NewHashEmbedder(256)
+
+	should.Equal(t, []string{"咱俩谁跟谁呀", "redis", "7", "2"}, segment("咱俩谁跟谁呀。Ｒｅｄｉｓ 7.2！"))
+	should.Equal(t, []string{"咱", "俩", "好", "ok"}, tokenize("咱俩好，OK？"))
+
+	a, err := embedder.Embed(ctx, "咱俩谁跟谁呀。")
+	should.Nil(t, err)
+	should.Equal(t, 256, len(a))
+	again, _ := embedder.Embed(ctx, "咱俩谁跟谁呀。")
+	should.Equal(t, a, again)
+	should.InDelta(t, 1, dot(a, a), 1e-9)
+
+	b, _ := embedder.Embed(ctx, "我俩谁跟谁呀。")
+	c, _ := embedder.Embed(ctx, "今天的天气很好")
+	should.Greater(t, dot(a, b), dot(a, c))
+
+	d, _ := embedder.Embed(ctx, "vector database")
+	e, _ := embedder.Embed(ctx, "Vector databases")
+	f, _ := embedder.Embed(ctx, "chat history")
+	should.Greater(t, dot(d, e), dot(d, f))
+
+	empty, _ := embedder.Embed(ctx, "。！？")
+	should.Equal(t, make([]float64, 256), empty)
+
+	for _, dim := range []int{0, -1} {
+		vec, err := NewHashEmbedder(dim).Embed(ctx, "咱俩谁跟谁呀。")
+		should.Nil(t, err)
+		should.Equal(t, DefaultVectorConfig.Dim, len(vec))
+	}
+}
+
+func dot(a, b []float64) (sum float64) {
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return
+}
@@ -1,13 +1,26 @@
 package redis4rag
 
 import (
+	"cmp"
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-func CreateIndex(cli *redis.Client, schema []*redis.FieldSchema, index string, prefix []interface{}) (err error) {
+// EnsureIndexOptions tunes EnsureIndex; a nil value uses the defaults.
+type EnsureIndexOptions struct {
+	// Progress is called with percent_indexed, from 0 to 1, every time FT.INFO is polled.
+	Progress func(percent float64)
+	// MinBackoff and MaxBackoff bound the delay between polls, 50ms and 2s by default.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func CreateIndex(cli redis.UniversalClient, schema []*redis.FieldSchema, index string, prefix []interface{}) (err error) {
 	cmd := cli.FTCreate(context.Background(), index,
 		&redis.FTCreateOptions{Prefix: prefix, OnJSON: true},
 		schema...)
@@ -15,15 +28,133 @@ func CreateIndex(cli *redis.Client, schema []*redis.FieldSchema, index string, p
 	if err != nil {
 		return err
 	}
+	return waitIndexed(context.Background(), cli, index, nil)
+}
+
+// EnsureIndex creates index unless it already exists with a matching schema, then waits until
+// the existing documents are indexed. It returns a SchemaDiff when the existing index differs,
+// and ctx.Err() once ctx is done, so it is safe to call on every deploy.
+func EnsureIndex(ctx context.Context, cli redis.UniversalClient, schema []*redis.FieldSchema, index string, prefix []interface{}, opts *EnsureIndexOptions) (err error) {
+	err = cli.FTCreate(ctx, index,
+		&redis.FTCreateOptions{Prefix: prefix, OnJSON: true},
+		schema...).Err()
+	if err != nil {
+		if !isIndexExists(err) {
+			return err
+		}
+		var diff SchemaDiff
+		if diff, err = VerifyIndex(ctx, cli, index, schema, prefix); err != nil {
+			return err
+		} else if len(diff) > 0 {
+			return diff
+		}
+	}
+	return waitIndexed(ctx, cli, index, opts)
+}
+
+// Reindex builds the next versioned index "{alias}:v{n}" over prefix next to the one alias points to,
+// waits until it has indexed the existing documents, then repoints alias with FT.ALIASUPDATE.
+// Searches through alias keep being served by the old index until the swap.
+// The old index is dropped, keeping its documents, when dropOld is set.
+func Reindex(ctx context.Context, cli redis.UniversalClient, schema []*redis.FieldSchema, alias string, prefix []interface{}, dropOld bool) (index string, err error) {
+	var current string
+	if current, err = aliasedIndex(ctx, cli, alias); err != nil {
+		return
+	}
+	if current == alias {
+		return "", fmt.Errorf("redis4rag: %s is an index, not an alias", alias)
+	}
+
+	index = fmt.Sprintf("%s:v%d", alias, indexVersion(alias, current)+1)
+	err = cli.FTCreate(ctx, index,
+		&redis.FTCreateOptions{Prefix: prefix, OnJSON: true},
+		schema...).Err()
+	if err != nil {
+		return
+	}
+	if err = waitIndexed(ctx, cli, index, nil); err != nil {
+		return
+	}
+	if err = cli.FTAliasUpdate(ctx, index, alias).Err(); err != nil {
+		return
+	}
+	if dropOld && len(current) > 0 {
+		err = cli.FTDropIndex(ctx, current).Err()
+	}
+	return
+}
+
+// ConvertIndex migrates the index behind alias to vectors of type typ, e.g. from FLOAT64 to FLOAT16,
+// by reindexing the existing documents into a retyped copy of schema, see Reindex and RetypeSchema.
+// Handles must query with the new type once alias is swapped: build them WithVectorConfig
+// or WithSchema(RetypeSchema(schema, typ)).
+func ConvertIndex(ctx context.Context, cli redis.UniversalClient, schema []*redis.FieldSchema, alias string, prefix []interface{}, typ string, dropOld bool) (string, error) {
+	if err := (VectorConfig{Type: typ}).Validate(); err != nil {
+		return "", err
+	}
+	return Reindex(ctx, cli, RetypeSchema(schema, typ), alias, prefix, dropOld)
+}
+
+// aliasedIndex returns the index alias points to, or "" when alias does not exist.
+func aliasedIndex(ctx context.Context, cli redis.UniversalClient, alias string) (string, error) {
+	res, err := cli.FTInfo(ctx, alias).Result()
+	if err != nil {
+		if isUnknownIndex(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return res.IndexName, nil
+}
+
+// indexVersion parses n out of "{alias}:v{n}", returning 0 for anything else.
+func indexVersion(alias, index string) int {
+	if v, ok := strings.CutPrefix(index, alias+":v"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+func isIndexExists(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "index already exists")
+}
+
+// waitIndexed polls FT.INFO with exponential backoff until index is no longer indexing.
+func waitIndexed(ctx context.Context, cli redis.UniversalClient, index string, opts *EnsureIndexOptions) error {
+	var o EnsureIndexOptions
+	if opts != nil {
+		o = *opts
+	}
+	delay := cmp.Or(o.MinBackoff, 50*time.Millisecond)
+	maxDelay := max(cmp.Or(o.MaxBackoff, 2*time.Second), delay)
 	for {
-		var res redis.FTInfoResult
-		res, err = cli.FTInfo(context.Background(), index).Result()
+		res, err := cli.FTInfo(ctx, index).Result()
 		if err != nil {
 			return err
 		}
+		if o.Progress != nil {
+			o.Progress(res.PercentIndexed)
+		}
 		if res.Indexing == 0 {
-			return
+			return nil
 		}
-		time.Sleep(100 * time.Millisecond)
+		if err = sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// sleep waits for d, returning early with ctx.Err() when ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
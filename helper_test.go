@@ -0,0 +1,129 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestIndexVersion(t *testing.T) {
+	should.Equal(t, 0, indexVersion("idx:docs", ""))
+	should.Equal(t, 0, indexVersion("idx:docs", "idx:docs"))
+	should.Equal(t, 0, indexVersion("idx:docs", "idx:docs:vx"))
+	should.Equal(t, 3, indexVersion("idx:docs", "idx:docs:v3"))
+	should.Equal(t, 0, indexVersion("idx:docs", "idx:other:v3"))
+}
+
+func TestReindex(t *testing.T) {
+	ctx := context.Background()
+	alias := "idx:test_reindex"
+	docprefix := "doc:test_reindex"
+	redisCli := testRedis(t)
+	redisCli.FTAliasDel(ctx, alias)
+	redisCli.FTDropIndexWithArgs(ctx, alias+":v1", &redis.FTDropIndexOptions{DeleteDocs: true})
+	redisCli.FTDropIndexWithArgs(ctx, alias+":v2", &redis.FTDropIndexOptions{DeleteDocs: true})
+
+	retriever, err := NewRetriever(ctx,
+		WithIndexAlias(alias),
+		WithPrefix(docprefix),
+		WithRedisClient(redisCli),
+		WithEmbedder(localEmbedder.Embedding),
+		WithCreateIndex(true))
+	should.Nil(t, err)
+
+	index, err := aliasedIndex(ctx, redisCli, alias)
+	should.Nil(t, err)
+	should.Equal(t, alias+":v1", index)
+
+	err = retriever.Store(ctx, &Document{Tag: "chatter", ID: "0", Content: "咱俩谁跟谁呀。"}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	index, err = Reindex(ctx, redisCli, DocumentSchema, alias, []interface{}{docprefix}, true)
+	should.Nil(t, err)
+	should.Equal(t, alias+":v2", index)
+
+	docs, err := retriever.Retrieve(ctx, "咱俩谁跟谁呀。", "", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+
+	should.Nil(t, redisCli.FTAliasDel(ctx, alias).Err())
+	should.Nil(t, redisCli.FTDropIndexWithArgs(ctx, index, &redis.FTDropIndexOptions{DeleteDocs: true}).Err())
+}
+
+func TestConvertIndex(t *testing.T) {
+	ctx := context.Background()
+	alias := "idx:test_convert_index"
+	docprefix := "doc:test_convert_index"
+	redisCli := testRedis(t)
+	redisCli.FTAliasDel(ctx, alias)
+	redisCli.FTDropIndexWithArgs(ctx, alias+":v1", &redis.FTDropIndexOptions{DeleteDocs: true})
+	redisCli.FTDropIndexWithArgs(ctx, alias+":v2", &redis.FTDropIndexOptions{DeleteDocs: true})
+
+	retriever, err := NewRetriever(ctx,
+		WithIndexAlias(alias),
+		WithPrefix(docprefix),
+		WithRedisClient(redisCli),
+		WithEmbedder(localEmbedder.Embedding),
+		WithCreateIndex(true))
+	should.Nil(t, err)
+	err = retriever.Store(ctx, &Document{Tag: "chatter", ID: "0", Content: "咱俩谁跟谁呀。"}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	_, err = ConvertIndex(ctx, redisCli, DocumentSchema, alias, []interface{}{docprefix}, "FLOAT8", true)
+	should.NotNil(t, err)
+	index, err := ConvertIndex(ctx, redisCli, DocumentSchema, alias, []interface{}{docprefix}, VectorTypeFloat16, true)
+	should.Nil(t, err)
+	should.Equal(t, alias+":v2", index)
+
+	retriever, err = NewRetriever(ctx,
+		WithIndexAlias(alias),
+		WithPrefix(docprefix),
+		WithRedisClient(redisCli),
+		WithEmbedder(localEmbedder.Embedding),
+		WithSchema(RetypeSchema(DocumentSchema, VectorTypeFloat16)),
+		WithVerifyIndex(true))
+	should.Nil(t, err)
+	docs, err := retriever.Retrieve(ctx, "咱俩谁跟谁呀。", "", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+
+	should.Nil(t, redisCli.FTAliasDel(ctx, alias).Err())
+	should.Nil(t, redisCli.FTDropIndexWithArgs(ctx, index, &redis.FTDropIndexOptions{DeleteDocs: true}).Err())
+}
+
+func TestEnsureIndex(t *testing.T) {
+	ctx := context.Background()
+	indexname := "idx:test_ensure_index"
+	docprefix := "doc:test_ensure_index"
+	redisCli := testRedis(t)
+	redisCli.FTDropIndexWithArgs(ctx, indexname, &redis.FTDropIndexOptions{DeleteDocs: true})
+
+	var progress []float64
+	opts := &EnsureIndexOptions{Progress: func(percent float64) { progress = append(progress, percent) }}
+	err := EnsureIndex(ctx, redisCli, DocumentSchema, indexname, []interface{}{docprefix}, opts)
+	should.Nil(t, err)
+	should.NotEmpty(t, progress)
+
+	err = EnsureIndex(ctx, redisCli, DocumentSchema, indexname, []interface{}{docprefix}, nil)
+	should.Nil(t, err)
+
+	err = EnsureIndex(ctx, redisCli, VectorConfig{Dim: 8}.DocumentSchema(), indexname, []interface{}{docprefix}, nil)
+	var diff SchemaDiff
+	should.ErrorAs(t, err, &diff)
+
+	should.Nil(t, redisCli.FTDropIndexWithArgs(ctx, indexname, &redis.FTDropIndexOptions{DeleteDocs: true}).Err())
+}
+
+func TestSleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	should.ErrorIs(t, sleep(ctx, time.Minute), context.Canceled)
+	should.Less(t, time.Since(start), time.Second)
+	should.Nil(t, sleep(context.Background(), time.Millisecond))
+}
@@ -0,0 +1,188 @@
+package redis4rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// HTTPEmbedderOptions configures OpenAIEmbedder and OllamaEmbedder, as well as TEIReranker and CohereReranker.
+	HTTPEmbedderOptions struct {
+		// BaseURL defaults to https://api.openai.com/v1 for OpenAI and http://localhost:11434 for Ollama.
+		BaseURL string
+		Model   string
+		// Dimensions asks the model for shortened vectors when it supports it, 0 keeps its default.
+		Dimensions int
+		// APIKey is sent as a bearer token when set.
+		APIKey  string
+		Headers map[string]string
+		// Timeout bounds every request, 30s by default.
+		Timeout time.Duration
+		// BatchSize caps the texts sent per request by EmbedBatch, DefaultBatchSize by default.
+		BatchSize int
+		// Client replaces the default http.Client, Timeout still applies per request.
+		Client *http.Client
+	}
+
+	// OpenAIEmbedder calls an OpenAI compatible POST {BaseURL}/embeddings endpoint,
+	// as served by OpenAI, vLLM, TEI, LocalAI and others.
+	OpenAIEmbedder struct {
+		opts HTTPEmbedderOptions
+	}
+
+	// OllamaEmbedder calls Ollama's POST {BaseURL}/api/embed endpoint.
+	OllamaEmbedder struct {
+		opts HTTPEmbedderOptions
+	}
+
+	// HTTPError is returned when an embedding or rerank endpoint answers with a non 2xx status.
+	HTTPError struct {
+		StatusCode int
+		Body       string
+	}
+)
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("redis4rag: http status %d: %s", e.StatusCode, e.Body)
+}
+
+func NewOpenAIEmbedder(opts HTTPEmbedderOptions) *OpenAIEmbedder {
+	if len(opts.BaseURL) == 0 {
+		opts.BaseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIEmbedder{opts: opts.withDefaults()}
+}
+
+// Embed is an Embedder.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	return embedOne(ctx, text, e.EmbedBatch)
+}
+
+// EmbedBatch is a BatchEmbedder.
+func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	return e.opts.batch(ctx, texts, func(ctx context.Context, texts []string) ([][]float64, error) {
+		req := struct {
+			Model          string   `json:"model"`
+			Input          []string `json:"input"`
+			Dimensions     int      `json:"dimensions,omitempty"`
+			EncodingFormat string   `json:"encoding_format"`
+		}{e.opts.Model, texts, e.opts.Dimensions, "float"}
+		var res struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float64 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := e.opts.post(ctx, "/embeddings", &req, &res); err != nil {
+			return nil, err
+		}
+		sort.Slice(res.Data, func(i, j int) bool { return res.Data[i].Index < res.Data[j].Index })
+		vecs := make([][]float64, len(res.Data))
+		for i, data := range res.Data {
+			vecs[i] = data.Embedding
+		}
+		return vecs, nil
+	})
+}
+
+func NewOllamaEmbedder(opts HTTPEmbedderOptions) *OllamaEmbedder {
+	if len(opts.BaseURL) == 0 {
+		opts.BaseURL = "http://localhost:11434"
+	}
+	return &OllamaEmbedder{opts: opts.withDefaults()}
+}
+
+// Embed is an Embedder.
+func (e *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	return embedOne(ctx, text, e.EmbedBatch)
+}
+
+// EmbedBatch is a BatchEmbedder.
+func (e *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	return e.opts.batch(ctx, texts, func(ctx context.Context, texts []string) ([][]float64, error) {
+		req := struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions,omitempty"`
+		}{e.opts.Model, texts, e.opts.Dimensions}
+		var res struct {
+			Embeddings [][]float64 `json:"embeddings"`
+		}
+		if err := e.opts.post(ctx, "/api/embed", &req, &res); err != nil {
+			return nil, err
+		}
+		return res.Embeddings, nil
+	})
+}
+
+func embedOne(ctx context.Context, text string, embedder BatchEmbedder) ([]float64, error) {
+	vecs, err := embedder(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("redis4rag: embedding endpoint returned %d vectors for 1 text", len(vecs))
+	}
+	return vecs[0], nil
+}
+
+func (opts HTTPEmbedderOptions) withDefaults() HTTPEmbedderOptions {
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return opts
+}
+
+// batch splits texts into requests of at most BatchSize texts.
+func (opts *HTTPEmbedderOptions) batch(ctx context.Context, texts []string, request BatchEmbedder) ([][]float64, error) {
+	vecs := make([][]float64, 0, len(texts))
+	err := forEachBatch(ctx, texts, opts.BatchSize, request, func(_, _ int, batch [][]float64) error {
+		vecs = append(vecs, batch...)
+		return nil
+	})
+	return vecs, err
+}
+
+func (opts *HTTPEmbedderOptions) post(ctx context.Context, path string, req, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.BaseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if len(opts.APIKey) > 0 {
+		httpReq.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+	for key, val := range opts.Headers {
+		httpReq.Header.Set(key, val)
+	}
+	httpRes, err := opts.Client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+	if httpRes.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(httpRes.Body, 4096))
+		return &HTTPError{StatusCode: httpRes.StatusCode, Body: string(msg)}
+	}
+	return json.NewDecoder(httpRes.Body).Decode(res)
+}
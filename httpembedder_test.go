@@ -0,0 +1,90 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		should.Equal(t, "/v1/embeddings", r.URL.Path)
+		should.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		should.Equal(t, "team-a", r.Header.Get("X-Team"))
+		var req map[string]interface{}
+		should.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		input := req["input"].([]interface{})
+		// answer out of order, the embedder must restore it by index
+		data := []map[string]interface{}{}
+		for i := len(input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float64{float64(len(input[i].(string)))}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	embedder := NewOpenAIEmbedder(HTTPEmbedderOptions{
+		BaseURL:    server.URL + "/v1/",
+		Model:      "text-embedding-3-small",
+		Dimensions: 256,
+		APIKey:     "sk-test",
+		Headers:    map[string]string{"X-Team": "team-a"},
+		BatchSize:  2,
+	})
+	vecs, err := embedder.EmbedBatch(context.Background(), []string{"a", "bb", "ccc"})
+	should.Nil(t, err)
+	should.Equal(t, [][]float64{{1}, {2}, {3}}, vecs)
+	should.Equal(t, 2, len(requests))
+	should.Equal(t, "text-embedding-3-small", requests[0]["model"])
+	should.Equal(t, float64(256), requests[0]["dimensions"])
+
+	vec, err := embedder.Embed(context.Background(), "dddd")
+	should.Nil(t, err)
+	should.Equal(t, []float64{4}, vec)
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		should.Equal(t, "/api/embed", r.URL.Path)
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		should.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		should.Equal(t, "bge-m3", req.Model)
+		if req.Input[0] == "slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if req.Input[0] == "bad" {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		embeddings := [][]float64{}
+		for _, text := range req.Input {
+			embeddings = append(embeddings, []float64{float64(len(text)), 1})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	defer server.Close()
+
+	embedder := NewOllamaEmbedder(HTTPEmbedderOptions{BaseURL: server.URL, Model: "bge-m3", Timeout: 50 * time.Millisecond})
+	vec, err := embedder.Embed(context.Background(), "abc")
+	should.Nil(t, err)
+	should.Equal(t, []float64{3, 1}, vec)
+
+	_, err = embedder.Embed(context.Background(), "bad")
+	var httpErr *HTTPError
+	should.True(t, errors.As(err, &httpErr))
+	should.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	_, err = embedder.Embed(context.Background(), "slow")
+	should.ErrorIs(t, err, context.DeadlineExceeded)
+}
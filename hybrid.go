@@ -0,0 +1,167 @@
+package redis4rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// FusionRRF ranks by reciprocal rank fusion, sum(weight / (K + rank)).
+	FusionRRF = "RRF"
+	// FusionLinear ranks by a weighted sum of the BM25 score, divided by the best one,
+	// and the vector similarity.
+	FusionLinear = "LINEAR"
+)
+
+type (
+	// Fusion configures a hybrid search, see WithHybrid.
+	Fusion struct {
+		// Method is FusionRRF by default.
+		Method string
+		// TextWeight and VectorWeight default to 1 each.
+		TextWeight   float64
+		VectorWeight float64
+		// K is the RRF rank constant, 60 by default.
+		K int
+		// Candidates is how many results each search contributes, 2*topK by default.
+		Candidates int
+	}
+
+	// HybridScore explains the rank of a hybrid search result. A document found by a single
+	// search has zero sub-score and rank for the other one, and a zero Score when it is the vector one.
+	HybridScore struct {
+		// Text is the BM25 score of the full-text search.
+		Text float64 `json:"text"`
+		// Vector is the similarity of the vector search.
+		Vector     float64 `json:"vector"`
+		TextRank   int     `json:"text_rank,omitempty"`
+		VectorRank int     `json:"vector_rank,omitempty"`
+		// Fused is the score results are sorted by, highest first.
+		Fused float64 `json:"fused"`
+	}
+)
+
+func (f Fusion) withDefaults() Fusion {
+	f.Method = cmp.Or(f.Method, FusionRRF)
+	if f.TextWeight == 0 && f.VectorWeight == 0 {
+		f.TextWeight, f.VectorWeight = 1, 1
+	}
+	f.K = cmp.Or(f.K, 60)
+	return f
+}
+
+// hybrid runs the vector search and a BM25 search on content matching any of its terms, then fuses them.
+func (r *Retriever) hybrid(ctx context.Context, content, filter, field string, cfg VectorConfig, vec []float64, topK int, so *searchOptions) ([]*ScoredDocument, error) {
+	fusion := so.fusion.withDefaults()
+	candidates := cmp.Or(fusion.Candidates, 2*topK)
+	vectorDocs, err := r.vectorSearch(ctx, filter, field, cfg, vec, candidates, so)
+	if err != nil {
+		return nil, err
+	}
+	textDocs, err := r.textSearch(ctx, content, filter, candidates)
+	if err != nil {
+		return nil, err
+	}
+	return fusion.fuse(textDocs, vectorDocs, topK), nil
+}
+
+// textSearch runs a BM25 search for the terms of content on DocTerms, which holds the same terms of
+// the stored content, so that CJK text matches by its bigrams.
+func (r *Retriever) textSearch(ctx context.Context, content, filter string, limit int) (docs []*ScoredDocument, err error) {
+	terms := lexicalTerms(content)
+	if len(terms) == 0 || limit <= 0 {
+		return
+	}
+	// terms are made of letters, digits and CJK characters only, none of which needs escaping;
+	// parents stored by StoreChunked hold the content of their chunks, which the vector search finds
+	query := fmt.Sprintf("@%s:(%s) -%s", DocTerms.As, strings.Join(terms, "|"), tagClause(DocRole.As, RoleParent))
+	if filter != "*" {
+		query = fmt.Sprintf("(%s) %s", filter, query)
+	}
+	opts := &redis.FTSearchOptions{
+		Return:         DocumentDefaultReturn,
+		DialectVersion: 2,
+		WithScores:     true,
+		Scorer:         "BM25",
+		Limit:          limit,
+	}
+	res, err := r.redisCli.FTSearchWithArgs(ctx, r.indexName, query, opts).Result()
+	if err != nil {
+		return
+	}
+	for _, raw := range res.Docs {
+		var parsed *Document
+		if parsed, err = parseDocument(&raw, r.metadata); err != nil {
+			return
+		}
+		doc := &ScoredDocument{Document: *parsed, Hybrid: &HybridScore{}}
+		if raw.Score != nil {
+			doc.Hybrid.Text = *raw.Score
+		}
+		docs = append(docs, doc)
+	}
+	return
+}
+
+// lexicalText is the value of DocTerms for content.
+func lexicalText(content string) string {
+	return strings.Join(lexicalTerms(content), " ")
+}
+
+// fuse merges the text and vector rankings by document ID into the topK best fused results.
+func (f Fusion) fuse(text, vector []*ScoredDocument, topK int) []*ScoredDocument {
+	var (
+		fused   []*ScoredDocument
+		byID    = make(map[string]*ScoredDocument, len(text)+len(vector))
+		maxText float64
+	)
+	merge := func(doc *ScoredDocument) *HybridScore {
+		if found, ok := byID[doc.ID]; ok {
+			return found.Hybrid
+		}
+		merged := &ScoredDocument{Document: doc.Document, Hybrid: &HybridScore{}}
+		byID[doc.ID] = merged
+		fused = append(fused, merged)
+		return merged.Hybrid
+	}
+	for i, doc := range text {
+		score := merge(doc)
+		score.Text, score.TextRank = doc.Hybrid.Text, i+1
+		maxText = max(maxText, score.Text)
+	}
+	for i, doc := range vector {
+		score := merge(doc)
+		score.Vector, score.VectorRank = doc.Similarity, i+1
+		byID[doc.ID].Score, byID[doc.ID].vector = doc.Score, doc.vector
+	}
+	for _, doc := range fused {
+		score := doc.Hybrid
+		switch f.Method {
+		case FusionLinear:
+			if maxText > 0 {
+				score.Fused = f.TextWeight * score.Text / maxText
+			}
+			score.Fused += f.VectorWeight * score.Vector
+		default:
+			if score.TextRank > 0 {
+				score.Fused = f.TextWeight / float64(f.K+score.TextRank)
+			}
+			if score.VectorRank > 0 {
+				score.Fused += f.VectorWeight / float64(f.K+score.VectorRank)
+			}
+		}
+	}
+	// stable, so ties keep the text ranking first
+	slices.SortStableFunc(fused, func(a, b *ScoredDocument) int {
+		return cmp.Compare(b.Hybrid.Fused, a.Hybrid.Fused)
+	})
+	if topK > 0 && len(fused) > topK {
+		fused = fused[:topK]
+	}
+	return fused
+}
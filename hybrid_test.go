@@ -0,0 +1,112 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitsark/redis4rag/chunking"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestFuse(t *testing.T) {
+	text := []*ScoredDocument{
+		{Document: Document{ID: "a"}, Hybrid: &HybridScore{Text: 4}},
+		{Document: Document{ID: "b"}, Hybrid: &HybridScore{Text: 2}},
+	}
+	vector := []*ScoredDocument{
+		{Document: Document{ID: "c"}, Score: Score{Distance: 0.2, Similarity: 0.9}},
+		{Document: Document{ID: "b"}, Score: Score{Distance: 0.4, Similarity: 0.8}},
+	}
+
+	fused := Fusion{}.withDefaults().fuse(text, vector, 0)
+	should.Equal(t, 3, len(fused))
+	should.Equal(t, "b", fused[0].ID)
+	should.Equal(t, HybridScore{Text: 2, Vector: 0.8, TextRank: 2, VectorRank: 2, Fused: 2.0 / 62}, *fused[0].Hybrid)
+	should.Equal(t, Score{Distance: 0.4, Similarity: 0.8}, fused[0].Score)
+	// a and c tie, the text ranking goes first
+	should.Equal(t, "a", fused[1].ID)
+	should.Equal(t, "c", fused[2].ID)
+	should.Equal(t, Score{}, fused[1].Score)
+
+	fused = Fusion{Method: FusionLinear, TextWeight: 0.3, VectorWeight: 0.7}.withDefaults().fuse(text, vector, 2)
+	should.Equal(t, 2, len(fused))
+	should.Equal(t, "b", fused[0].ID)
+	should.InDelta(t, 0.3*0.5+0.7*0.8, fused[0].Hybrid.Fused, 1e-9)
+	should.Equal(t, "c", fused[1].ID)
+	should.InDelta(t, 0.7*0.9, fused[1].Hybrid.Fused, 1e-9)
+
+	fused = Fusion{VectorWeight: 1}.withDefaults().fuse(text, vector, 1)
+	should.Equal(t, "c", fused[0].ID)
+}
+
+func TestLexicalText(t *testing.T) {
+	should.Equal(t, "错误 误码 e1042 表示 示磁 磁盘 盘已 已满", lexicalText("错误码E1042表示磁盘已满。"))
+	should.Equal(t, "disk is full", lexicalText("Disk is FULL!"))
+}
+
+func TestRetrievalHybrid(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_hybrid")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "Error E1042 means the disk is full"},
+		{Tag: "faq", ID: "1", Content: "What does error code mean"},
+		{Tag: "faq", ID: "2", Content: "How do I reset my password?"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	for _, fusion := range []Fusion{{}, {Method: FusionLinear, TextWeight: 0.5, VectorWeight: 0.5}} {
+		docs, err := retriever.Retrieve(ctx, "what does E1042 mean", "faq", 2, nil, WithHybrid(fusion))
+		should.Nil(t, err)
+		should.Equal(t, 2, len(docs))
+		should.Equal(t, "0", docs[0].ID)
+		should.Greater(t, docs[0].Hybrid.Text, 0.0)
+		should.Greater(t, docs[0].Hybrid.Vector, 0.0)
+		should.GreaterOrEqual(t, docs[0].Hybrid.Fused, docs[1].Hybrid.Fused)
+	}
+}
+
+func TestRetrievalHybridChunked(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_hybrid_chunked")
+
+	doc := &Document{ID: "faq", Tag: "faq", Content: "Error E1042 means the disk is full. Reset your password from the login page."}
+	should.Nil(t, retriever.StoreChunked(ctx, doc, &chunking.Sentence{Size: 40}, nil))
+	time.Sleep(100 * time.Millisecond)
+
+	// the parent holds the terms of every chunk but only its chunks are found
+	docs, err := retriever.Retrieve(ctx, "what does E1042 mean", "faq", 3, nil, WithHybrid(Fusion{}))
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "faq#0", docs[0].ID)
+	should.Greater(t, docs[0].Hybrid.Text, 0.0)
+	for _, doc := range docs {
+		should.NotEqual(t, "faq", doc.ID)
+	}
+
+	docs, err = retriever.Retrieve(ctx, "what does E1042 mean", "faq", 1, nil, WithHybrid(Fusion{}), WithParentDocument())
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "faq", docs[0].ID)
+}
+
+func TestRetrievalHybridCJK(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_hybrid_cjk")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "错误码E1042表示磁盘已满，请清理磁盘空间。"},
+		{Tag: "faq", ID: "1", Content: "如何重置我的密码？"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "磁盘满了怎么办", "faq", 2, nil, WithHybrid(Fusion{}))
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "0", docs[0].ID)
+	should.Greater(t, docs[0].Hybrid.Text, 0.0)
+	should.Equal(t, 0.0, docs[1].Hybrid.Text)
+}
@@ -0,0 +1,132 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hashTag wraps s in braces so that every key sharing it maps to the same cluster slot.
+func hashTag(s string) string {
+	return "{" + s + "}"
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+// scanKeys calls fn with each batch of keys matching pattern.
+// Cluster and ring clients are scanned on every master/shard, since SCAN only sees one node.
+func scanKeys(ctx context.Context, cli redis.UniversalClient, pattern string, fn func([]string) error) error {
+	scan := func(ctx context.Context, node redis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := node.Scan(ctx, cursor, pattern, 512).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err = fn(keys); err != nil {
+					return err
+				}
+			}
+			if cursor = next; cursor == 0 {
+				return nil
+			}
+		}
+	}
+	switch c := cli.(type) {
+	case *redis.ClusterClient:
+		return c.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	case *redis.Ring:
+		return c.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+	default:
+		return scan(ctx, cli)
+	}
+}
+
+// delKeys deletes keys one command each, so a cluster pipeline never sends a cross-slot DEL,
+// and counts the ones that existed.
+func delKeys(ctx context.Context, cli redis.UniversalClient, keys []string) (deleted int64, err error) {
+	pipeline := cli.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipeline.Del(ctx, key)
+	}
+	if _, err = pipeline.Exec(ctx); err != nil {
+		return
+	}
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return
+}
+
+// migrateKeys moves every JSON document under prefix whose key differs from the one key derives
+// from it, as written before keys were hash tagged, to that key, keeping its TTL. When the new key
+// already exists, as after re-storing the document, the old copy is only deleted. Values for which
+// key reports false are left alone. It returns how many old keys it removed.
+func migrateKeys(ctx context.Context, cli redis.UniversalClient, prefix string, key func(value json.RawMessage) (string, bool)) (migrated int64, err error) {
+	err = scanKeys(ctx, cli, escapeGlob(prefix)+":*", func(keys []string) error {
+		pipeline := cli.Pipeline()
+		values := make([]*redis.JSONCmd, len(keys))
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, old := range keys {
+			values[i] = pipeline.JSONGet(ctx, old, "$")
+			ttls[i] = pipeline.PTTL(ctx, old)
+		}
+		// keys of another type fail on their own, they are not ours to move
+		pipeline.Exec(ctx)
+
+		var (
+			moves []int
+			tos   []string
+			sets  []*redis.StatusCmd
+		)
+		pipeline = cli.Pipeline()
+		for i, old := range keys {
+			var matches []json.RawMessage
+			if values[i].Err() != nil || json.Unmarshal([]byte(values[i].Val()), &matches) != nil || len(matches) == 0 {
+				continue
+			}
+			to, ok := key(matches[0])
+			if !ok || to == old {
+				continue
+			}
+			moves, tos = append(moves, i), append(tos, to)
+			sets = append(sets, pipeline.JSONSetMode(ctx, to, "$", string(matches[0]), "NX"))
+		}
+		if len(moves) == 0 {
+			return nil
+		}
+		// JSON.SET NX replies nil when the new key exists
+		if _, err := pipeline.Exec(ctx); err != nil && err != redis.Nil {
+			return err
+		}
+
+		pipeline = cli.Pipeline()
+		dels := make([]*redis.IntCmd, len(moves))
+		for j, i := range moves {
+			if ttl := ttls[i].Val(); ttl > 0 && sets[j].Err() == nil {
+				pipeline.PExpire(ctx, tos[j], ttl)
+			}
+			dels[j] = pipeline.Del(ctx, keys[i])
+		}
+		if _, err := pipeline.Exec(ctx); err != nil {
+			return err
+		}
+		for _, del := range dels {
+			migrated += del.Val()
+		}
+		return nil
+	})
+	return
+}
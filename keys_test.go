@@ -0,0 +1,83 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestKeys(t *testing.T) {
+	retriever := &Retriever{docPrefix: "doc:test"}
+	should.Equal(t, "doc:test:{42}", retriever.key("42"))
+
+	cache := &LLMsCache{docPrefix: "qa:test"}
+	should.Equal(t, "qa:test:{"+makeCacheKey("hello")+"}", cache.key("hello"))
+
+	history := &ChatHistory{docPrefix: "chat:test"}
+	should.Equal(t, "chat:test:{u1}:s1:100", history.key(&ChatMessage{UserId: "u1", SessionId: "s1", Timestamp: 100}))
+
+	should.Equal(t, `a\*b\?c\[d\]\\`, escapeGlob(`a*b?c[d]\`))
+}
+
+func TestMigrateKeys(t *testing.T) {
+	ctx := context.Background()
+	redisCli := testRedis(t)
+	defer redisCli.Close()
+	retriever := &Retriever{docPrefix: "doc:test_migrate_keys", redisCli: redisCli}
+	cache := &LLMsCache{docPrefix: "qa:test_migrate_keys", redisCli: redisCli}
+	history := &ChatHistory{docPrefix: "chat:test_migrate_keys", redisCli: redisCli}
+	clean := func() {
+		for _, prefix := range []string{retriever.docPrefix, cache.docPrefix, history.docPrefix} {
+			keys, _ := redisCli.Keys(ctx, prefix+":*").Result()
+			if len(keys) > 0 {
+				redisCli.Del(ctx, keys...)
+			}
+		}
+	}
+	clean()
+	defer clean()
+
+	// keys as written before hash tagging
+	should.Nil(t, redisCli.JSONSet(ctx, retriever.docPrefix+":0", "$", `{"id":"0","content":"old"}`).Err())
+	should.Nil(t, redisCli.JSONSet(ctx, retriever.docPrefix+":1", "$", `{"id":"1","content":"old"}`).Err())
+	should.Nil(t, redisCli.JSONSet(ctx, retriever.key("1"), "$", `{"id":"1","content":"new"}`).Err())
+	should.Nil(t, redisCli.Set(ctx, retriever.docPrefix+":not-json", "x", 0).Err())
+	should.Nil(t, redisCli.JSONSet(ctx, cache.docPrefix+":"+makeCacheKey("hi"), "$", `{"query":"hi","answer":"hello"}`).Err())
+	should.Nil(t, redisCli.Expire(ctx, cache.docPrefix+":"+makeCacheKey("hi"), time.Hour).Err())
+	should.Nil(t, redisCli.JSONSet(ctx, history.docPrefix+":u1:s1:100", "$", `{"user_id":"u1","session_id":"s1","timestamp":100}`).Err())
+
+	n, err := retriever.MigrateKeys(ctx)
+	should.Nil(t, err)
+	should.Equal(t, int64(2), n)
+	content, err := redisCli.JSONGet(ctx, retriever.key("0"), "$.content").Result()
+	should.Nil(t, err)
+	should.Equal(t, `["old"]`, content)
+	// the newer copy wins
+	content, err = redisCli.JSONGet(ctx, retriever.key("1"), "$.content").Result()
+	should.Nil(t, err)
+	should.Equal(t, `["new"]`, content)
+	exists, err := redisCli.Exists(ctx, retriever.docPrefix+":0", retriever.docPrefix+":1", retriever.docPrefix+":not-json").Result()
+	should.Nil(t, err)
+	should.Equal(t, int64(1), exists)
+
+	n, err = cache.MigrateKeys(ctx)
+	should.Nil(t, err)
+	should.Equal(t, int64(1), n)
+	ttl, err := redisCli.TTL(ctx, cache.key("hi")).Result()
+	should.Nil(t, err)
+	should.Greater(t, ttl, 59*time.Minute)
+
+	n, err = history.MigrateKeys(ctx)
+	should.Nil(t, err)
+	should.Equal(t, int64(1), n)
+	exists, err = redisCli.Exists(ctx, history.key(&ChatMessage{UserId: "u1", SessionId: "s1", Timestamp: 100})).Result()
+	should.Nil(t, err)
+	should.Equal(t, int64(1), exists)
+
+	// nothing left to move
+	n, err = retriever.MigrateKeys(ctx)
+	should.Nil(t, err)
+	should.Equal(t, int64(0), n)
+}
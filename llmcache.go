@@ -17,6 +17,8 @@ var (
 		QAQuery,
 		QAAnswer,
 		QAQueryVec,
+		EmbeddingModelId,
+		EmbeddingModelVersion,
 	}
 
 	QueryAnswerDefaultReturn = []redis.FTSearchReturn{
@@ -34,22 +36,19 @@ var (
 	QAAnswer = &redis.FieldSchema{
 		FieldName: "$.answer", As: "answer", FieldType: redis.SearchFieldTypeText, NoIndex: true,
 	}
-	QAQueryVec = &redis.FieldSchema{
-		FieldName: "$.query_vec", As: "query_vec", FieldType: redis.SearchFieldTypeVector,
-		VectorArgs: &redis.FTVectorArgs{
-			FlatOptions: &redis.FTFlatOptions{
-				Type:           "FLOAT64",
-				Dim:            1024,
-				DistanceMetric: "COSINE",
-			},
-		}}
+	QAQueryVec = DefaultVectorConfig.Field("$.query_vec", "query_vec")
 )
 
 type (
 	LLMsCache struct {
 		indexName string
 		docPrefix string
-		redisCli  *redis.Client
+		redisCli  redis.UniversalClient
+		embedder  Embedder
+		batcher   BatchEmbedder
+		batchSize int
+		vector    VectorConfig
+		model     EmbeddingModel
 	}
 
 	QueryAnswer struct {
@@ -59,24 +58,87 @@ type (
 	}
 )
 
+// LLMCacheSchema generates the LLM cache schema with cfg as its vector field.
+func (cfg VectorConfig) LLMCacheSchema() []*redis.FieldSchema {
+	return []*redis.FieldSchema{
+		QATag,
+		QAQuery,
+		QAAnswer,
+		cfg.Field(QAQueryVec.FieldName, QAQueryVec.As),
+		EmbeddingModelId,
+		EmbeddingModelVersion,
+	}
+}
+
+func NewLLMsCache(ctx context.Context, opts ...Option) (*LLMsCache, error) {
+	o := newOptions("idx:llm_cache", "doc:llm_cache", VectorConfig.LLMCacheSchema, opts...)
+	if err := o.retrieverOnly("LLMsCache"); err != nil {
+		return nil, err
+	}
+	if err := o.init(ctx); err != nil {
+		return nil, err
+	}
+	return &LLMsCache{
+		indexName: o.indexName,
+		docPrefix: o.docPrefix,
+		redisCli:  o.redisCli,
+		embedder:  o.embedder,
+		batcher:   o.batcher,
+		batchSize: o.batchSize,
+		vector:    *o.vector,
+		model:     o.model,
+	}, nil
+}
+
 func (cache *LLMsCache) Cache(ctx context.Context, qa *QueryAnswer, embedder Embedder) (err error) {
 	var vec []float64
-	if vec, err = embedder(ctx, qa.Query); err != nil {
+	if vec, err = embed(ctx, qa.Query, embedder, cache.embedder); err != nil {
 		return
 	}
-	var jsonData []byte
-	if jsonData, err = json.Marshal(qa); err != nil {
+	pipeline := cache.redisCli.Pipeline()
+	if err = cache.write(ctx, pipeline, qa, vec); err != nil {
 		return
 	}
-	pipeline := cache.redisCli.Pipeline()
-	// key pattern: {LLMsCache.DocPrefix}:md5({QueryAnswer.Query})
-	key := fmt.Sprintf("%s:%s", cache.docPrefix, makeCacheKey(qa.Query))
-	pipeline.JSONSet(ctx, key, "$", string(jsonData))
-	pipeline.JSONSet(ctx, key, QAQueryVec.FieldName, vec)
 	_, err = pipeline.Exec(ctx)
 	return err
 }
 
+// CacheBatch embeds and caches qas in chunks of the configured batch size, one pipeline per chunk.
+func (cache *LLMsCache) CacheBatch(ctx context.Context, qas []*QueryAnswer, embedder BatchEmbedder) error {
+	texts := make([]string, len(qas))
+	for i, qa := range qas {
+		texts[i] = qa.Query
+	}
+	return forEachBatch(ctx, texts, cache.batchSize, batchOf(embedder, cache.batcher, cache.embedder), func(lo, hi int, vecs [][]float64) error {
+		pipeline := cache.redisCli.Pipeline()
+		for i, qa := range qas[lo:hi] {
+			if err := cache.write(ctx, pipeline, qa, vecs[i]); err != nil {
+				return err
+			}
+		}
+		_, err := pipeline.Exec(ctx)
+		return err
+	})
+}
+
+func (cache *LLMsCache) write(ctx context.Context, pipeline redis.Pipeliner, qa *QueryAnswer, vec []float64) error {
+	vec, err := cache.vector.prepare(vec)
+	if err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(qa)
+	if err != nil {
+		return err
+	}
+	key := cache.key(qa.Query)
+	pipeline.JSONSet(ctx, key, "$", string(jsonData))
+	pipeline.JSONSet(ctx, key, QAQueryVec.FieldName, cache.vector.jsonValue(vec))
+	if len(cache.model.ID) > 0 {
+		pipeline.JSONSet(ctx, key, "$.embedding", cache.model)
+	}
+	return nil
+}
+
 func (cache *LLMsCache) Lookup(ctx context.Context, queryText string) (qa *QueryAnswer, err error) {
 	opts := &redis.FTSearchOptions{
 		Return:         QueryAnswerDefaultReturn,
@@ -91,25 +153,39 @@ func (cache *LLMsCache) Lookup(ctx context.Context, queryText string) (qa *Query
 	return
 }
 
-func (cache *LLMsCache) SemanticSearch(ctx context.Context, tag string, queryText string, embedder Embedder) (qa *QueryAnswer, err error) {
+func (cache *LLMsCache) SemanticSearch(ctx context.Context, tag string, queryText string, embedder Embedder, searchOpts ...SearchOption) (qa *ScoredQueryAnswer, err error) {
+	so := newSearchOptions(searchOpts...)
+	if err = so.retrieverOnly("LLMsCache"); err != nil {
+		return
+	}
 	var vec []float64
-	if vec, err = embedder(ctx, queryText); err != nil {
+	if vec, err = embed(ctx, queryText, embedder, cache.embedder); err != nil {
+		return
+	}
+	if vec, err = cache.vector.prepare(vec); err != nil {
 		return
 	}
 	opts := &redis.FTSearchOptions{
-		Return:         QueryAnswerDefaultReturn,
+		Return:         withScore(QueryAnswerDefaultReturn),
 		DialectVersion: 2,
-		Params:         map[string]interface{}{"vec": []byte(vector2string(vec))},
+		Params:         map[string]interface{}{"vec": []byte(vector2string(vec, cache.vector.Type))},
 		SortBy: []redis.FTSearchSortBy{
-			{FieldName: "score", Asc: true},
+			{FieldName: scoreField, Asc: true},
 		},
 	}
-	filter := fmt.Sprintf("@%s:{%s}", QATag.As, strings.ReplaceAll(tag, ",", "|"))
-	query := fmt.Sprintf("(%s)=>[KNN %d @%s $vec AS %s]", filter, 1, QAQueryVec.As, "score")
+	var tagFilter string
+	if len(tag) > 0 {
+		tagFilter = tagClause(QATag.As, strings.Split(tag, ",")...)
+	}
+	filter := so.filter(tagFilter)
+	query := fmt.Sprintf("(%s)=>[%s]", filter, cache.vector.knn(1, QAQueryVec.As, scoreField))
 	cmd := cache.redisCli.FTSearchWithArgs(ctx, cache.indexName, query, opts)
 	var result redis.FTSearchResult
 	if result, err = cmd.Result(); err == nil && result.Total > 0 {
-		qa = parseQueryAnswer(&result.Docs[0])
+		// a hit too far away is a miss
+		if score := cache.vector.score(&result.Docs[0]); so.accept(cache.vector, score.Distance) {
+			qa = &ScoredQueryAnswer{QueryAnswer: *parseQueryAnswer(&result.Docs[0]), Score: score}
+		}
 	}
 	return
 }
@@ -129,6 +205,25 @@ func parseQueryAnswer(doc *redis.Document) *QueryAnswer {
 	return &qa
 }
 
+// key pattern: {LLMsCache.DocPrefix}:{md5({QueryAnswer.Query})}, hash tagged on the digest
+func (cache *LLMsCache) key(query string) string {
+	return fmt.Sprintf("%s:%s", cache.docPrefix, hashTag(makeCacheKey(query)))
+}
+
+// MigrateKeys moves the entries stored as {DocPrefix}:md5({QueryAnswer.Query}) by versions before
+// keys were hash tagged to their current key, and returns how many it moved. Run it once after upgrading.
+func (cache *LLMsCache) MigrateKeys(ctx context.Context) (int64, error) {
+	return migrateKeys(ctx, cache.redisCli, cache.docPrefix, func(value json.RawMessage) (string, bool) {
+		var qa struct {
+			Query *string `json:"query"`
+		}
+		if json.Unmarshal(value, &qa) != nil || qa.Query == nil {
+			return "", false
+		}
+		return cache.key(*qa.Query), true
+	})
+}
+
 func makeCacheKey(text string) string {
 	hash := md5.Sum([]byte(text))
 	return hex.EncodeToString(hash[:])
@@ -1,9 +1,7 @@
 package redis4rag
 
 import (
-	"cmp"
 	"context"
-	"os"
 	"testing"
 
 	"github.com/redis/go-redis/v9"
@@ -14,23 +12,17 @@ func TestLLMCacheBasic(t *testing.T) {
 	ctx := context.Background()
 	indexname := "idx:test_llm_cache_basic"
 	docprefix := "doc:test_llm_cache_basic"
-	redisCli := redis.NewClient(&redis.Options{
-		Addr:     cmp.Or(os.Getenv("REDIS_URL"), "localhost:6379"),
-		Protocol: 2,
-	})
+	redisCli := testRedis(t)
 	redisCli.FTDropIndexWithArgs(ctx, indexname, &redis.FTDropIndexOptions{DeleteDocs: true})
 
-	err := CreateIndex(redisCli, LLMCacheSchema, indexname, []interface{}{docprefix})
+	cache, err := NewLLMsCache(ctx,
+		WithIndexName(indexname),
+		WithPrefix(docprefix),
+		WithRedisClient(redisCli),
+		WithCreateIndex(true))
 	should.Nil(t, err)
 	t.Logf("index %s created", indexname)
 
-	//TODO
-	cache := &LLMsCache{
-		indexName: indexname,
-		docPrefix: docprefix,
-		redisCli:  redisCli,
-	}
-
 	err = cache.Cache(ctx, &QueryAnswer{
 		Tag:    "chatter",
 		Query:  "咱俩谁跟谁呀。",
@@ -60,6 +52,29 @@ func TestLLMCacheBasic(t *testing.T) {
 		t.Log(qa)
 	}
 
+	{
+		qa, err := cache.SemanticSearch(ctx, "chatter", "咱俩关系不错呀。", localEmbedder.Embedding,
+			WithFilter(Not(Text(QAQuery.As, "咱俩谁跟谁呀。"))))
+		should.Nil(t, err)
+		should.NotNil(t, qa)
+		should.Equal(t, "我俩谁跟谁呀。", qa.Query)
+	}
+
+	{
+		err := cache.Cache(ctx, &QueryAnswer{Tag: "small-talk", Query: "咱俩关系很好。", Answer: "是的"}, localEmbedder.Embedding)
+		should.Nil(t, err)
+		qa, err := cache.SemanticSearch(ctx, "small-talk", "咱俩关系不错呀。", localEmbedder.Embedding)
+		should.Nil(t, err)
+		should.NotNil(t, qa)
+		should.Equal(t, "咱俩关系很好。", qa.Query)
+
+		// no tag searches every tag
+		qa, err = cache.SemanticSearch(ctx, "", "咱俩关系很好。", localEmbedder.Embedding)
+		should.Nil(t, err)
+		should.NotNil(t, qa)
+		should.Equal(t, "咱俩关系很好。", qa.Query)
+	}
+
 	err = redisCli.FTDropIndexWithArgs(context.Background(), indexname, &redis.FTDropIndexOptions{DeleteDocs: true}).Err()
 	should.Nil(t, err)
 	t.Logf("index %s dropped", indexname)
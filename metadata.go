@@ -0,0 +1,147 @@
+package redis4rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	MetadataTag     = "TAG"
+	MetadataNumeric = "NUMERIC"
+	MetadataText    = "TEXT"
+	MetadataGeo     = "GEO"
+)
+
+// DocMetadata is the JSON path of Document.Metadata.
+const DocMetadata = "$.metadata"
+
+type (
+	// MetadataField declares an indexed Document.Metadata entry, stored at $.metadata.{Name} and
+	// indexed under Name, so that filters can use it, e.g. Tag("tenant", "X") or Gte("year", 2023).
+	// Name may only contain letters, digits and underscores.
+	MetadataField struct {
+		Name string
+		// Type is one of MetadataTag, MetadataNumeric, MetadataText and MetadataGeo.
+		Type     string
+		Sortable bool
+	}
+
+	// GeoPoint is the value of a MetadataGeo entry, stored as "lon,lat".
+	GeoPoint struct {
+		Lon float64
+		Lat float64
+	}
+)
+
+// WithMetadataField indexes the Document.Metadata entry declared by field. Only NewRetriever accepts it.
+func WithMetadataField(field MetadataField) Option {
+	return func(o *options) { o.metadata = append(o.metadata, field) }
+}
+
+func (f MetadataField) field() *redis.FieldSchema {
+	schema := &redis.FieldSchema{FieldName: DocMetadata + "." + f.Name, As: f.Name, Sortable: f.Sortable}
+	switch f.Type {
+	case MetadataTag:
+		schema.FieldType, schema.Separator = redis.SearchFieldTypeTag, ","
+	case MetadataNumeric:
+		schema.FieldType = redis.SearchFieldTypeNumeric
+	case MetadataText:
+		schema.FieldType = redis.SearchFieldTypeText
+	case MetadataGeo:
+		schema.FieldType = redis.SearchFieldTypeGeo
+	}
+	return schema
+}
+
+func (f MetadataField) validate() error {
+	switch f.Type {
+	case MetadataTag, MetadataNumeric, MetadataText, MetadataGeo:
+	default:
+		return fmt.Errorf("redis4rag: unsupported metadata type %q of %q", f.Type, f.Name)
+	}
+	if !validVectorName(f.Name) {
+		return fmt.Errorf("redis4rag: invalid metadata name %q", f.Name)
+	}
+	return nil
+}
+
+// decode converts a JSON metadata value to the Go type of the field:
+// string for TAG and TEXT, or []string for a list of tags, float64 for NUMERIC and GeoPoint for GEO.
+func (f MetadataField) decode(raw json.RawMessage) (any, error) {
+	switch f.Type {
+	case MetadataNumeric:
+		var n float64
+		err := json.Unmarshal(raw, &n)
+		return n, err
+	case MetadataGeo:
+		var p GeoPoint
+		err := json.Unmarshal(raw, &p)
+		return p, err
+	case MetadataTag:
+		var tags []string
+		if err := json.Unmarshal(raw, &tags); err == nil {
+			return tags, nil
+		}
+	}
+	var s string
+	err := json.Unmarshal(raw, &s)
+	return s, err
+}
+
+// parseMetadata decodes the $.metadata object returned by a search, undeclared entries as plain JSON values.
+func parseMetadata(raw string, fields []MetadataField) (map[string]any, error) {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(firstMatch(raw)), &entries); err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]any, len(entries))
+	for name, value := range entries {
+		var (
+			v   any
+			err error
+		)
+		if i := indexOfMetadata(fields, name); i >= 0 {
+			v, err = fields[i].decode(value)
+		} else {
+			err = json.Unmarshal(value, &v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis4rag: metadata %q: %w", name, err)
+		}
+		metadata[name] = v
+	}
+	return metadata, nil
+}
+
+func indexOfMetadata(fields []MetadataField, name string) int {
+	for i, f := range fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p GeoPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(p.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lat, 'f', -1, 64))
+}
+
+func (p *GeoPoint) UnmarshalJSON(data []byte) (err error) {
+	var s string
+	if err = json.Unmarshal(data, &s); err != nil {
+		return
+	}
+	lon, lat, ok := strings.Cut(s, ",")
+	if !ok {
+		return fmt.Errorf("redis4rag: invalid geo point %q", s)
+	}
+	if p.Lon, err = strconv.ParseFloat(strings.TrimSpace(lon), 64); err != nil {
+		return
+	}
+	p.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	return
+}
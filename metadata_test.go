@@ -0,0 +1,87 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	should "github.com/stretchr/testify/assert"
+)
+
+var testMetadata = []MetadataField{
+	{Name: "tenant", Type: MetadataTag},
+	{Name: "year", Type: MetadataNumeric, Sortable: true},
+	{Name: "title", Type: MetadataText},
+	{Name: "location", Type: MetadataGeo},
+}
+
+func TestMetadataField(t *testing.T) {
+	should.Equal(t, &redis.FieldSchema{FieldName: "$.metadata.tenant", As: "tenant", FieldType: redis.SearchFieldTypeTag, Separator: ","}, testMetadata[0].field())
+	should.Equal(t, &redis.FieldSchema{FieldName: "$.metadata.year", As: "year", FieldType: redis.SearchFieldTypeNumeric, Sortable: true}, testMetadata[1].field())
+	should.Equal(t, redis.SearchFieldTypeGeo, testMetadata[3].field().FieldType)
+	should.NotNil(t, MetadataField{Name: "year", Type: "DATE"}.validate())
+	should.NotNil(t, MetadataField{Name: "the year", Type: MetadataNumeric}.validate())
+
+	data, err := json.Marshal(&Document{ID: "0", Metadata: map[string]any{"location": GeoPoint{Lon: 116.4, Lat: 39.9}, "year": 2024}})
+	should.Nil(t, err)
+	should.Equal(t, `{"id":"0","tag":"","content":"","payload":"","metadata":{"location":"116.4,39.9","year":2024}}`, string(data))
+
+	raw := `[{"tenant":"x","year":2024,"title":"Redis","location":"116.4,39.9","tags":["a","b"],"extra":{"k":1}}]`
+	metadata, err := parseMetadata(raw, append(testMetadata, MetadataField{Name: "tags", Type: MetadataTag}))
+	should.Nil(t, err)
+	should.Equal(t, map[string]any{
+		"tenant":   "x",
+		"year":     2024.0,
+		"title":    "Redis",
+		"location": GeoPoint{Lon: 116.4, Lat: 39.9},
+		"tags":     []string{"a", "b"},
+		"extra":    map[string]any{"k": 1.0},
+	}, metadata)
+
+	_, err = parseMetadata(`{"location":"east"}`, testMetadata)
+	should.NotNil(t, err)
+
+	doc, err := parseDocument(&redis.Document{Fields: map[string]string{DocId.FieldName: "0", DocMetadata: `{"year":2023}`}}, testMetadata)
+	should.Nil(t, err)
+	should.Equal(t, &Document{ID: "0", Metadata: map[string]any{"year": 2023.0}}, doc)
+	_, err = parseDocument(&redis.Document{Fields: map[string]string{DocMetadata: `{"location":"east"}`}}, testMetadata)
+	should.ErrorContains(t, err, `redis4rag: metadata "location"`)
+}
+
+func TestRetrievalMetadata(t *testing.T) {
+	ctx := context.Background()
+	redisCli := testRedis(t)
+	_, err := NewRetriever(ctx, WithRedisClient(redisCli), WithMetadataField(MetadataField{Name: "tag", Type: MetadataTag}))
+	should.NotNil(t, err)
+	_, err = NewLLMsCache(ctx, WithRedisClient(redisCli), WithMetadataField(testMetadata[0]))
+	should.ErrorContains(t, err, "LLMsCache does not support metadata fields")
+	_, err = NewChatHistory(ctx, WithRedisClient(redisCli), WithMetadataField(testMetadata[0]))
+	should.ErrorContains(t, err, "ChatHistory does not support metadata fields")
+
+	var opts []Option
+	for _, f := range testMetadata {
+		opts = append(opts, WithMetadataField(f))
+	}
+	retriever := newTestRetriever(t, "retrieval_metadata", opts...)
+
+	err = retriever.StoreBatch(ctx, []*Document{
+		{ID: "0", Content: "如何重置我的密码？", Metadata: map[string]any{"tenant": "x", "year": 2024, "title": "password", "location": GeoPoint{Lon: 116.4, Lat: 39.9}}},
+		{ID: "1", Content: "如何重置我的密码？", Metadata: map[string]any{"tenant": "x", "year": 2022, "title": "password"}},
+		{ID: "2", Content: "如何重置我的密码？", Metadata: map[string]any{"tenant": "y", "year": 2024, "title": "draft"}},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "如何重置我的密码？", "", 3, nil,
+		WithFilter(And(Tag("tenant", "x"), Gte("year", 2023), Not(Text("title", "draft")))))
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "0", docs[0].ID)
+	should.Equal(t, map[string]any{"tenant": "x", "year": 2024.0, "title": "password", "location": GeoPoint{Lon: 116.4, Lat: 39.9}}, docs[0].Metadata)
+
+	docs, err = retriever.Retrieve(ctx, "如何重置我的密码？", "", 3, nil, WithFilter(Geo("location", 116.4, 39.9, 1, GeoUnitKilometers)))
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+}
@@ -0,0 +1,342 @@
+package redis4rag
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("redis4rag: embedder circuit breaker is open")
+
+type (
+	// Middleware decorates an Embedder. Retry, Timeout, RateLimiter and CircuitBreaker implement it
+	// and keep their state, so the same value should not wrap unrelated embedders.
+	Middleware interface {
+		Wrap(Embedder) Embedder
+	}
+
+	// Retry retries failed calls with exponential backoff and full jitter. The zero value is usable.
+	Retry struct {
+		// MaxAttempts counts the first call, 3 by default.
+		MaxAttempts int
+		// BaseDelay and MaxDelay bound the backoff, 100ms and 5s by default.
+		BaseDelay time.Duration
+		MaxDelay  time.Duration
+		// Retryable reports whether err is transient, IsRetryable by default.
+		Retryable func(error) bool
+
+		calls    atomic.Uint64
+		retries  atomic.Uint64
+		failures atomic.Uint64
+	}
+
+	// Timeout bounds every call with Duration, calls are not bounded when it is <= 0.
+	Timeout struct {
+		Duration time.Duration
+
+		timeouts atomic.Uint64
+	}
+
+	// RateLimiter is a token bucket allowing Rate calls per second with bursts of up to Burst calls.
+	// Calls wait for a token, or until their context is done.
+	RateLimiter struct {
+		Rate  float64
+		Burst int
+
+		mu      sync.Mutex
+		started bool
+		tokens  float64
+		last    time.Time
+		waits   atomic.Uint64
+	}
+
+	// CircuitBreaker fails fast with ErrCircuitOpen once at least FailureRate of the last Window
+	// calls failed, after a minimum of MinRequests calls, capped at Window. After Cooldown it lets a
+	// single trial call through: a success closes the circuit again, a failure reopens it. Calls
+	// canceled by their caller are not counted, a canceled trial leaves the circuit half-open.
+	CircuitBreaker struct {
+		// FailureRate defaults to 0.5, MinRequests to 10, Window to 20 calls and Cooldown to 30s.
+		FailureRate float64
+		MinRequests int
+		Window      int
+		Cooldown    time.Duration
+
+		mu       sync.Mutex
+		state    CircuitState
+		outcomes []bool
+		next     int
+		openedAt time.Time
+		trial    bool
+		rejected atomic.Uint64
+	}
+
+	CircuitState int
+
+	RetryStats struct {
+		Calls    uint64
+		Retries  uint64
+		Failures uint64
+	}
+)
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+// Chain wraps embedder with mws, the first middleware being the outermost, e.g.
+// Chain(e, &Retry{}, &CircuitBreaker{}, &RateLimiter{Rate: 10}, &Timeout{Duration: time.Second}).
+func Chain(embedder Embedder, mws ...Middleware) Embedder {
+	for i := len(mws) - 1; i >= 0; i-- {
+		embedder = mws[i].Wrap(embedder)
+	}
+	return embedder
+}
+
+// IsRetryable treats network errors, per call timeouts, HTTP 429 and 5xx as transient, and
+// canceled contexts, an open circuit and other HTTP statuses as permanent.
+func IsRetryable(err error) bool {
+	var httpErr *HTTPError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrNoEmbedder):
+		return false
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	return true
+}
+
+func (r *Retry) Wrap(next Embedder) Embedder {
+	return func(ctx context.Context, text string) (vec []float64, err error) {
+		r.calls.Add(1)
+		attempts := cmp.Or(r.MaxAttempts, 3)
+		base := cmp.Or(r.BaseDelay, 100*time.Millisecond)
+		ceiling := max(cmp.Or(r.MaxDelay, 5*time.Second), base)
+		retryable := r.Retryable
+		if retryable == nil {
+			retryable = IsRetryable
+		}
+		for attempt := 0; ; attempt++ {
+			if vec, err = next(ctx, text); err == nil {
+				return
+			}
+			if attempt+1 >= attempts || !retryable(err) || ctx.Err() != nil {
+				r.failures.Add(1)
+				return
+			}
+			r.retries.Add(1)
+			backoff := min(base<<attempt, ceiling)
+			if backoff <= 0 {
+				backoff = ceiling
+			}
+			if err := sleep(ctx, rand.N(backoff+1)); err != nil {
+				r.failures.Add(1)
+				return nil, err
+			}
+		}
+	}
+}
+
+func (r *Retry) Stats() RetryStats {
+	return RetryStats{Calls: r.calls.Load(), Retries: r.retries.Load(), Failures: r.failures.Load()}
+}
+
+func (t *Timeout) Wrap(next Embedder) Embedder {
+	if t.Duration <= 0 {
+		return next
+	}
+	return func(ctx context.Context, text string) ([]float64, error) {
+		ctx, cancel := context.WithTimeout(ctx, t.Duration)
+		defer cancel()
+		vec, err := next(ctx, text)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.timeouts.Add(1)
+		}
+		return vec, err
+	}
+}
+
+// Timeouts counts the calls that ran out of time.
+func (t *Timeout) Timeouts() uint64 {
+	return t.timeouts.Load()
+}
+
+func (l *RateLimiter) Wrap(next Embedder) Embedder {
+	return func(ctx context.Context, text string) ([]float64, error) {
+		if wait := l.reserve(); wait > 0 {
+			l.waits.Add(1)
+			if err := sleep(ctx, wait); err != nil {
+				l.cancel()
+				return nil, err
+			}
+		}
+		return next(ctx, text)
+	}
+}
+
+// Tokens reports how many calls may start right now without waiting, negative when calls are queued.
+func (l *RateLimiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	return l.tokens
+}
+
+// Waits counts the calls that had to wait for a token.
+func (l *RateLimiter) Waits() uint64 {
+	return l.waits.Load()
+}
+
+// reserve takes a token, possibly borrowing from the future, and returns how long to wait for it.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.tokens--
+	if l.tokens >= 0 || l.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.Rate * float64(time.Second))
+}
+
+// cancel returns the token of a call that gave up waiting.
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	burst := float64(max(l.Burst, 1))
+	if !l.started {
+		l.started, l.tokens, l.last = true, burst, now
+		return
+	}
+	l.tokens = min(burst, l.tokens+now.Sub(l.last).Seconds()*l.Rate)
+	l.last = now
+}
+
+func (b *CircuitBreaker) Wrap(next Embedder) Embedder {
+	return func(ctx context.Context, text string) ([]float64, error) {
+		if !b.allow() {
+			b.rejected.Add(1)
+			return nil, ErrCircuitOpen
+		}
+		vec, err := next(ctx, text)
+		if err != nil && errors.Is(err, context.Canceled) {
+			// the caller giving up says nothing about the health of the provider
+			b.abandon()
+		} else {
+			b.record(err == nil)
+		}
+		return vec, err
+	}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown() {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Rejected counts the calls failed fast with ErrCircuitOpen.
+func (b *CircuitBreaker) Rejected() uint64 {
+	return b.rejected.Load()
+}
+
+// ErrorRate is the failure rate over the current window.
+func (b *CircuitBreaker) ErrorRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.errorRate()
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown() {
+			return false
+		}
+		b.state, b.trial = CircuitHalfOpen, true
+		return true
+	case CircuitHalfOpen:
+		// a single trial call at a time
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// abandon ends a call without recording it, letting another trial call through when half-open.
+func (b *CircuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.trial = false
+	}
+}
+
+func (b *CircuitBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.trial = false
+		if ok {
+			b.state, b.outcomes, b.next = CircuitClosed, nil, 0
+		} else {
+			b.state, b.openedAt = CircuitOpen, time.Now()
+		}
+		return
+	}
+	window := cmp.Or(b.Window, 20)
+	if len(b.outcomes) < window {
+		b.outcomes = append(b.outcomes, ok)
+	} else {
+		b.outcomes[b.next] = ok
+		b.next = (b.next + 1) % window
+	}
+	if b.state == CircuitClosed && len(b.outcomes) >= min(cmp.Or(b.MinRequests, 10), window) && b.errorRate() >= cmp.Or(b.FailureRate, 0.5) {
+		b.state, b.openedAt = CircuitOpen, time.Now()
+	}
+}
+
+func (b *CircuitBreaker) errorRate() float64 {
+	if len(b.outcomes) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, ok := range b.outcomes {
+		if !ok {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(b.outcomes))
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	return cmp.Or(b.Cooldown, 30*time.Second)
+}
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
@@ -0,0 +1,142 @@
+package redis4rag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	flaky := func(_ context.Context, _ string) ([]float64, error) {
+		if calls++; calls < 3 {
+			return nil, &HTTPError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return []float64{1}, nil
+	}
+
+	retry := &Retry{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	vec, err := Chain(flaky, retry)(ctx, "text")
+	should.Nil(t, err)
+	should.Equal(t, []float64{1}, vec)
+	should.Equal(t, RetryStats{Calls: 1, Retries: 2}, retry.Stats())
+
+	calls = 0
+	_, err = Chain(flaky, &Retry{MaxAttempts: 2, BaseDelay: time.Millisecond})(ctx, "text")
+	should.NotNil(t, err)
+	should.Equal(t, 2, calls)
+
+	calls = 0
+	permanent := &Retry{BaseDelay: time.Millisecond}
+	_, err = permanent.Wrap(func(context.Context, string) ([]float64, error) {
+		calls++
+		return nil, &HTTPError{StatusCode: http.StatusUnauthorized}
+	})(ctx, "text")
+	should.NotNil(t, err)
+	should.Equal(t, 1, calls)
+	should.Equal(t, RetryStats{Calls: 1, Failures: 1}, permanent.Stats())
+}
+
+func TestTimeout(t *testing.T) {
+	timeout := &Timeout{Duration: 10 * time.Millisecond}
+	slow := timeout.Wrap(func(ctx context.Context, _ string) ([]float64, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	_, err := slow(context.Background(), "text")
+	should.ErrorIs(t, err, context.DeadlineExceeded)
+	should.Equal(t, uint64(1), timeout.Timeouts())
+
+	// the zero value does not bound calls
+	vec, err := (&Timeout{}).Wrap(func(ctx context.Context, _ string) ([]float64, error) {
+		_, bounded := ctx.Deadline()
+		should.False(t, bounded)
+		return []float64{1}, nil
+	})(context.Background(), "text")
+	should.Nil(t, err)
+	should.Equal(t, []float64{1}, vec)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := &RateLimiter{Rate: 100, Burst: 2}
+	embedder := limiter.Wrap(func(context.Context, string) ([]float64, error) { return nil, nil })
+
+	start := time.Now()
+	for range 4 {
+		_, err := embedder(context.Background(), "text")
+		should.Nil(t, err)
+	}
+	// two calls come from the burst, the other two wait 10ms each
+	should.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	should.Equal(t, uint64(2), limiter.Waits())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter = &RateLimiter{Rate: 0.001, Burst: 1}
+	embedder = limiter.Wrap(func(context.Context, string) ([]float64, error) { return nil, nil })
+	_, err := embedder(ctx, "text")
+	should.Nil(t, err)
+	_, err = embedder(ctx, "text")
+	should.ErrorIs(t, err, context.Canceled)
+	should.InDelta(t, 0, limiter.Tokens(), 0.01)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	failing, canceled := true, false
+	breaker := &CircuitBreaker{FailureRate: 0.5, MinRequests: 4, Window: 4, Cooldown: 20 * time.Millisecond}
+	embedder := breaker.Wrap(func(context.Context, string) ([]float64, error) {
+		if canceled {
+			return nil, context.Canceled
+		}
+		if failing {
+			return nil, errors.New("unavailable")
+		}
+		return []float64{1}, nil
+	})
+
+	for range 4 {
+		_, err := embedder(context.Background(), "text")
+		should.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	should.Equal(t, CircuitOpen, breaker.State())
+	should.Equal(t, 1.0, breaker.ErrorRate())
+
+	_, err := embedder(context.Background(), "text")
+	should.ErrorIs(t, err, ErrCircuitOpen)
+	should.Equal(t, uint64(1), breaker.Rejected())
+
+	// a failed trial reopens the circuit
+	time.Sleep(25 * time.Millisecond)
+	should.Equal(t, CircuitHalfOpen, breaker.State())
+	_, err = embedder(context.Background(), "text")
+	should.NotErrorIs(t, err, ErrCircuitOpen)
+	should.Equal(t, CircuitOpen, breaker.State())
+
+	// a canceled one proves nothing
+	time.Sleep(25 * time.Millisecond)
+	canceled = true
+	_, err = embedder(context.Background(), "text")
+	should.ErrorIs(t, err, context.Canceled)
+	should.Equal(t, CircuitHalfOpen, breaker.State())
+	canceled = false
+
+	// a successful one closes it
+	failing = false
+	_, err = embedder(context.Background(), "text")
+	should.Nil(t, err)
+	should.Equal(t, CircuitClosed, breaker.State())
+	should.Equal(t, "closed", breaker.State().String())
+
+	// MinRequests is capped at Window
+	small := &CircuitBreaker{Window: 5}
+	embedder = small.Wrap(func(context.Context, string) ([]float64, error) { return nil, errors.New("unavailable") })
+	for range 5 {
+		embedder(context.Background(), "text")
+	}
+	should.Equal(t, CircuitOpen, small.State())
+}
@@ -0,0 +1,76 @@
+package redis4rag
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// mmr configures maximal marginal relevance, see WithMMR.
+type mmr struct {
+	lambda float64
+	fetchK int
+}
+
+func (m *mmr) candidates(topK int) int {
+	if m.fetchK > 0 {
+		return max(m.fetchK, topK)
+	}
+	return 4 * topK
+}
+
+// rerank picks topK of candidates, sorted by relevance, in MMR order.
+func (m *mmr) rerank(candidates []*ScoredDocument, topK int) []*ScoredDocument {
+	if topK <= 0 || topK > len(candidates) {
+		topK = len(candidates)
+	}
+	var (
+		selected   = make([]*ScoredDocument, 0, topK)
+		picked     = make([]bool, len(candidates))
+		redundancy = make([]float64, len(candidates))
+	)
+	for len(selected) < topK {
+		best, bestScore := -1, math.Inf(-1)
+		for i, doc := range candidates {
+			if picked[i] {
+				continue
+			}
+			if score := m.lambda*doc.Similarity - (1-m.lambda)*redundancy[i]; score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		selected = append(selected, candidates[best])
+		for i, doc := range candidates {
+			if !picked[i] && doc.vector != nil && candidates[best].vector != nil {
+				redundancy[i] = max(redundancy[i], (1+cosine(doc.vector, candidates[best].vector))/2)
+			}
+		}
+	}
+	return selected
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// parseVector decodes a vector returned by a search as a JSON array, possibly wrapped in another one.
+func parseVector(raw string) []float64 {
+	var vec []float64
+	if json.Unmarshal([]byte(raw), &vec) == nil {
+		return vec
+	}
+	var matches [][]float64
+	if json.Unmarshal([]byte(raw), &matches) == nil && len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestMMR(t *testing.T) {
+	candidates := []*ScoredDocument{
+		{Document: Document{ID: "a"}, Score: Score{Similarity: 0.9}, vector: []float64{1, 0}},
+		{Document: Document{ID: "b"}, Score: Score{Similarity: 0.85}, vector: []float64{1, 0.01}},
+		{Document: Document{ID: "c"}, Score: Score{Similarity: 0.6}, vector: []float64{0, 1}},
+		{Document: Document{ID: "d"}, Score: Score{Similarity: 0.6}, vector: []float64{0, 1}},
+	}
+	ids := func(docs []*ScoredDocument) (ids []string) {
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		return
+	}
+	should.Equal(t, []string{"a", "b", "c", "d"}, ids((&mmr{lambda: 1}).rerank(candidates, 0)))
+	should.Equal(t, []string{"a", "c", "b"}, ids((&mmr{lambda: 0.5}).rerank(candidates, 3)))
+	should.Equal(t, []string{"a", "c"}, ids((&mmr{lambda: 0.5}).rerank(candidates, 2)))
+	// deterministic, ties going to the better ranked candidate
+	for range 10 {
+		should.Equal(t, []string{"a", "c", "b", "d"}, ids((&mmr{lambda: 0.5}).rerank(candidates, 10)))
+	}
+	// documents without vectors are never penalized
+	should.Equal(t, []string{"a", "b"}, ids((&mmr{lambda: 0.5}).rerank([]*ScoredDocument{
+		{Document: Document{ID: "a"}, Score: Score{Similarity: 0.9}, vector: []float64{1, 0}},
+		{Document: Document{ID: "b"}, Score: Score{Similarity: 0.85}},
+		{Document: Document{ID: "c"}, Score: Score{Similarity: 0.6}, vector: []float64{0, 1}},
+	}, 2)))
+
+	should.Equal(t, 20, (&mmr{lambda: 0.5}).candidates(5))
+	should.Equal(t, 8, (&mmr{lambda: 0.5, fetchK: 8}).candidates(5))
+	should.Equal(t, 10, (&mmr{lambda: 0.5, fetchK: 8}).candidates(10))
+
+	should.Equal(t, []float64{0.5, -1}, parseVector("[0.5,-1]"))
+	should.Equal(t, []float64{0.5, -1}, parseVector("[[0.5,-1]]"))
+	should.Nil(t, parseVector(""))
+	should.InDelta(t, 0.0, cosine([]float64{1, 0}, []float64{0, 0}), 1e-9)
+}
+
+func TestRetrievalMMR(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_mmr")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "How do I reset my password?"},
+		{Tag: "faq", ID: "1", Content: "How can I reset my password?"},
+		{Tag: "faq", ID: "2", Content: "Password reset link expired"},
+		{Tag: "faq", ID: "3", Content: "Change the account email"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "reset my password", "faq", 2, nil)
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "0", docs[0].ID)
+	should.Equal(t, "1", docs[1].ID)
+
+	// the near duplicate gives way to a more diverse answer
+	for range 3 {
+		docs, err = retriever.Retrieve(ctx, "reset my password", "faq", 2, nil, WithMMR(0.5, 0))
+		should.Nil(t, err)
+		should.Equal(t, 2, len(docs))
+		should.Equal(t, "0", docs[0].ID)
+		should.Equal(t, "2", docs[1].ID)
+		should.Greater(t, docs[1].Similarity, 0.0)
+	}
+
+	docs, err = retriever.Retrieve(ctx, "reset my password", "faq", 2, nil, WithMMR(1, 4))
+	should.Nil(t, err)
+	should.Equal(t, "1", docs[1].ID)
+}
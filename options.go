@@ -0,0 +1,208 @@
+package redis4rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrNoRedisClient = errors.New("redis4rag: redis client is required")
+
+type (
+	Option func(*options)
+
+	options struct {
+		indexName   string
+		alias       string
+		docPrefix   string
+		redisCli    redis.UniversalClient
+		embedder    Embedder
+		batcher     BatchEmbedder
+		batchSize   int
+		schema      []*redis.FieldSchema
+		vector      *VectorConfig
+		named       []NamedVector
+		metadata    []MetadataField
+		model       EmbeddingModel
+		createIndex bool
+		verifyIndex bool
+	}
+)
+
+// WithIndexName sets the name of the search index queried by the handle.
+func WithIndexName(name string) Option {
+	return func(o *options) { o.indexName = name }
+}
+
+// WithIndexAlias makes the handle query through alias instead of the index name.
+// Indexes created for it are versioned as "{alias}:v{n}" so that Reindex can swap them.
+func WithIndexAlias(alias string) Option {
+	return func(o *options) { o.alias = alias }
+}
+
+// WithPrefix sets the key prefix of the JSON documents covered by the index.
+func WithPrefix(prefix string) Option {
+	return func(o *options) { o.docPrefix = prefix }
+}
+
+// WithRedisClient sets the client, which may be a standalone, Sentinel, Cluster or Ring client.
+func WithRedisClient(cli redis.UniversalClient) Option {
+	return func(o *options) { o.redisCli = cli }
+}
+
+// WithEmbedder sets the embedder used when a method is called with a nil one.
+func WithEmbedder(embedder Embedder) Option {
+	return func(o *options) { o.embedder = embedder }
+}
+
+// WithBatchEmbedder sets the embedder used by batch methods called with a nil one.
+// Without it they fall back to the WithEmbedder one, called once per text.
+func WithBatchEmbedder(embedder BatchEmbedder) Option {
+	return func(o *options) { o.batcher = embedder }
+}
+
+// WithBatchSize sets how many texts batch methods embed and write per pipeline, DefaultBatchSize by default.
+func WithBatchSize(size int) Option {
+	return func(o *options) { o.batchSize = size }
+}
+
+// WithEmbeddingModel records model in $.embedding of every vector written by the handle.
+func WithEmbeddingModel(model EmbeddingModel) Option {
+	return func(o *options) { o.model = model }
+}
+
+// WithVectorConfig sets the vector field the default schema is generated with.
+func WithVectorConfig(cfg VectorConfig) Option {
+	return func(o *options) { o.vector = &cfg }
+}
+
+// WithNamedVector adds a named vector field to the retriever's documents, next to the content vector.
+// It is embedded on every Store and can be searched with WithVectorField. Only NewRetriever accepts it.
+func WithNamedVector(v NamedVector) Option {
+	return func(o *options) { o.named = append(o.named, v) }
+}
+
+// WithSchema overrides the generated schema used to create the index.
+// Unless WithVectorConfig is given, the vector config is taken from its vector field.
+func WithSchema(schema []*redis.FieldSchema) Option {
+	return func(o *options) { o.schema = schema }
+}
+
+// WithCreateIndex creates the index through EnsureIndex when it does not exist yet.
+func WithCreateIndex(create bool) Option {
+	return func(o *options) { o.createIndex = create }
+}
+
+// WithVerifyIndex makes the constructor fail with a SchemaDiff when the existing index
+// does not match the schema, see VerifyIndex.
+func WithVerifyIndex(verify bool) Option {
+	return func(o *options) { o.verifyIndex = verify }
+}
+
+func newOptions(indexName, docPrefix string, schemaOf func(VectorConfig) []*redis.FieldSchema, opts ...Option) *options {
+	o := &options{
+		indexName: indexName,
+		docPrefix: docPrefix,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.vector == nil {
+		cfg := DefaultVectorConfig
+		if o.schema != nil {
+			cfg, _ = vectorConfigOf(o.schema)
+		}
+		o.vector = &cfg
+	}
+	*o.vector = o.vector.withDefaults()
+	for i := range o.named {
+		o.named[i].Config = o.named[i].Config.withDefaults()
+	}
+	if o.schema == nil {
+		o.schema = schemaOf(*o.vector)
+		for _, v := range o.named {
+			o.schema = append(o.schema, v.field())
+		}
+		for _, f := range o.metadata {
+			o.schema = append(o.schema, f.field())
+		}
+	}
+	if len(o.alias) > 0 {
+		o.indexName = o.alias
+	}
+	return o
+}
+
+// retrieverOnly rejects the options that only make sense for the documents of a Retriever,
+// since they would add fields handle never writes to its index.
+func (o *options) retrieverOnly(handle string) error {
+	if len(o.named) > 0 {
+		return fmt.Errorf("redis4rag: %s does not support named vectors", handle)
+	}
+	if len(o.metadata) > 0 {
+		return fmt.Errorf("redis4rag: %s does not support metadata fields", handle)
+	}
+	return nil
+}
+
+func (o *options) init(ctx context.Context) (err error) {
+	if o.redisCli == nil {
+		return ErrNoRedisClient
+	}
+	if err = o.vector.Validate(); err != nil {
+		return
+	}
+	names := map[string]bool{}
+	for _, v := range o.named {
+		if !validVectorName(v.Name) || names[v.Name] {
+			return fmt.Errorf("redis4rag: invalid or duplicate vector name %q", v.Name)
+		}
+		names[v.Name] = true
+		if err = v.Config.Validate(); err != nil {
+			return
+		}
+	}
+	aliases := map[string]int{}
+	for _, field := range o.schema {
+		aliases[field.As]++
+	}
+	for _, f := range o.metadata {
+		if err = f.validate(); err != nil {
+			return
+		}
+		// the schema holds its own field, and no other one under that name
+		if aliases[f.Name] > 1 {
+			return fmt.Errorf("redis4rag: metadata name %q clashes with another field", f.Name)
+		}
+	}
+	if !o.createIndex && !o.verifyIndex {
+		return
+	}
+	prefix := []interface{}{o.docPrefix}
+	if err = o.redisCli.FTInfo(ctx, o.indexName).Err(); err != nil {
+		if !o.createIndex || !isUnknownIndex(err) {
+			return
+		}
+		if len(o.alias) > 0 {
+			_, err = Reindex(ctx, o.redisCli, o.schema, o.alias, prefix, false)
+			return
+		}
+		return EnsureIndex(ctx, o.redisCli, o.schema, o.indexName, prefix, nil)
+	}
+	if o.verifyIndex {
+		var diff SchemaDiff
+		if diff, err = VerifyIndex(ctx, o.redisCli, o.indexName, o.schema, prefix); err == nil && len(diff) > 0 {
+			err = diff
+		}
+	}
+	return
+}
+
+func isUnknownIndex(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unknown index") || strings.Contains(msg, "no such index")
+}
@@ -0,0 +1,108 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	_, err := NewRetriever(context.Background())
+	should.ErrorIs(t, err, ErrNoRedisClient)
+
+	redisCli := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer redisCli.Close()
+
+	retriever, err := NewRetriever(context.Background(), WithRedisClient(redisCli))
+	should.Nil(t, err)
+	should.Equal(t, "idx:documents", retriever.indexName)
+	should.Equal(t, "doc:documents", retriever.docPrefix)
+
+	cache, err := NewLLMsCache(context.Background(),
+		WithRedisClient(redisCli),
+		WithIndexName("idx:qa"),
+		WithPrefix("doc:qa"))
+	should.Nil(t, err)
+	should.Equal(t, "idx:qa", cache.indexName)
+	should.Equal(t, "doc:qa", cache.docPrefix)
+
+	history, err := NewChatHistory(context.Background(), WithRedisClient(redisCli))
+	should.Nil(t, err)
+	should.Equal(t, "idx:chat_history", history.indexName)
+
+	_, err = history.search(context.Background(), 0, ChatMessageUserId.As, "u", "text", nil)
+	should.ErrorIs(t, err, ErrNoEmbedder)
+}
+
+func TestNewWithVectorConfig(t *testing.T) {
+	redisCli := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer redisCli.Close()
+
+	retriever, err := NewRetriever(context.Background(),
+		WithRedisClient(redisCli),
+		WithVectorConfig(VectorConfig{Dim: 768, Type: VectorTypeFloat32}))
+	should.Nil(t, err)
+	should.Equal(t, 768, retriever.vector.Dim)
+	should.Equal(t, DistanceMetricCosine, retriever.vector.Metric)
+
+	cache, err := NewLLMsCache(context.Background(),
+		WithRedisClient(redisCli),
+		WithSchema(VectorConfig{Dim: 256, Metric: DistanceMetricL2}.LLMCacheSchema()))
+	should.Nil(t, err)
+	should.Equal(t, 256, cache.vector.Dim)
+	should.Equal(t, DistanceMetricL2, cache.vector.Metric)
+
+	_, err = NewChatHistory(context.Background(),
+		WithRedisClient(redisCli),
+		WithVectorConfig(VectorConfig{Type: "INT4"}))
+	should.NotNil(t, err)
+}
+
+func TestNewWithNamedVector(t *testing.T) {
+	redisCli := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer redisCli.Close()
+
+	title := NamedVector{
+		Name:     "title",
+		Config:   VectorConfig{Dim: 64, Type: VectorTypeFloat32},
+		Embedder: NewHashEmbedder(64).Embed,
+		Text:     func(doc *Document) string { return doc.Tag },
+	}
+	o := newOptions("idx", "doc", VectorConfig.DocumentSchema, WithRedisClient(redisCli), WithNamedVector(title))
+	should.Nil(t, o.init(context.Background()))
+	should.Equal(t, len(DocumentSchema)+1, len(o.schema))
+	should.Equal(t, "$.vectors.title", o.schema[len(o.schema)-1].FieldName)
+	should.Equal(t, "vec_title", o.schema[len(o.schema)-1].As)
+	should.Equal(t, 64, o.schema[len(o.schema)-1].VectorArgs.FlatOptions.Dim)
+
+	retriever, err := NewRetriever(context.Background(), WithRedisClient(redisCli), WithNamedVector(title))
+	should.Nil(t, err)
+	as, cfg, embedder, err := retriever.vectorField("title", nil)
+	should.Nil(t, err)
+	should.Equal(t, "vec_title", as)
+	should.Equal(t, VectorTypeFloat32, cfg.Type)
+	should.NotNil(t, embedder)
+	as, _, _, err = retriever.vectorField("", nil)
+	should.Nil(t, err)
+	should.Equal(t, DocContentVec.As, as)
+	_, _, _, err = retriever.vectorField("body", nil)
+	should.NotNil(t, err)
+
+	named, err := retriever.embedNamed(context.Background(), []*Document{{Tag: "a"}, {Tag: "b"}}, nil)
+	should.Nil(t, err)
+	should.Equal(t, 2, len(named))
+	should.Len(t, named[1]["title"], 64)
+
+	_, err = NewRetriever(context.Background(), WithRedisClient(redisCli), WithNamedVector(NamedVector{Name: "bad name"}))
+	should.NotNil(t, err)
+	_, err = NewRetriever(context.Background(), WithRedisClient(redisCli), WithNamedVector(title), WithNamedVector(title))
+	should.NotNil(t, err)
+
+	// the cache and the chat history never write named vectors
+	_, err = NewLLMsCache(context.Background(), WithRedisClient(redisCli), WithNamedVector(title))
+	should.ErrorContains(t, err, "LLMsCache does not support named vectors")
+	_, err = NewChatHistory(context.Background(), WithRedisClient(redisCli), WithNamedVector(title))
+	should.ErrorContains(t, err, "ChatHistory does not support named vectors")
+}
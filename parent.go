@@ -0,0 +1,161 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/bitsark/redis4rag/chunking"
+)
+
+// RoleParent is the DocRole of the documents stored by StoreChunked.
+const RoleParent = "parent"
+
+// parentOverfetch is how many chunks per parent a WithParentDocument search fetches,
+// so that topK distinct parents are found even when several chunks of one parent hit.
+const parentOverfetch = 4
+
+// StoreChunked splits doc with splitter, see SplitDocument, and stores its embedded chunks next to
+// doc itself. doc is stored without a vector, so that only its chunks are searched, and records
+// their IDs in Document.Chunks; each chunk links back to it in Document.Chunk. doc is marked with
+// DocRole RoleParent, which ReembedJob skips. Chunks left over from a previous version of doc are deleted.
+func (r *Retriever) StoreChunked(ctx context.Context, doc *Document, splitter chunking.Splitter, embedder BatchEmbedder) error {
+	chunks := SplitDocument(doc, splitter)
+	parent := *doc
+	parent.Chunks = make([]string, len(chunks))
+	for i, chunk := range chunks {
+		parent.Chunks[i] = chunk.ID
+	}
+	old, err := r.getDocuments(ctx, []string{doc.ID})
+	if err != nil {
+		return err
+	}
+	if err = r.StoreBatch(ctx, chunks, embedder); err != nil {
+		return err
+	}
+	data, err := json.Marshal(&parent)
+	if err != nil {
+		return err
+	}
+	pipeline := r.redisCli.Pipeline()
+	pipeline.JSONSet(ctx, r.key(doc.ID), "$", string(data))
+	pipeline.JSONSet(ctx, r.key(doc.ID), DocRole.FieldName, `"`+RoleParent+`"`)
+	if old[0] != nil {
+		for _, id := range old[0].Chunks {
+			if !slices.Contains(parent.Chunks, id) {
+				pipeline.Del(ctx, r.key(id))
+			}
+		}
+	}
+	_, err = pipeline.Exec(ctx)
+	return err
+}
+
+// parents replaces chunk hits by their parent documents, in the order of their best chunk,
+// keeping the first topK distinct ones. Hits that are not chunks are kept as they are.
+func (r *Retriever) parents(ctx context.Context, hits []*ScoredDocument, topK int) ([]*ScoredDocument, error) {
+	var (
+		docs []*ScoredDocument
+		ids  []string
+		seen = map[string]bool{}
+	)
+	for _, hit := range hits {
+		id := hit.ID
+		if hit.Chunk != nil {
+			id = hit.Chunk.ParentID
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		docs = append(docs, hit)
+		ids = append(ids, id)
+		if topK > 0 && len(docs) == topK {
+			break
+		}
+	}
+	parents, err := r.getDocuments(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, hit := range docs {
+		if hit.Chunk != nil && parents[i] != nil {
+			docs[i] = &ScoredDocument{Document: *parents[i], Score: hit.Score, Hybrid: hit.Hybrid}
+		}
+	}
+	return docs, nil
+}
+
+// windows sets the Window of every chunk hit to the chunk and its n neighbours on each side.
+func (r *Retriever) windows(ctx context.Context, hits []*ScoredDocument, n int) error {
+	var parentIDs []string
+	for _, hit := range hits {
+		if hit.Chunk != nil && !slices.Contains(parentIDs, hit.Chunk.ParentID) {
+			parentIDs = append(parentIDs, hit.Chunk.ParentID)
+		}
+	}
+	parents, err := r.getDocuments(ctx, parentIDs)
+	if err != nil {
+		return err
+	}
+	var (
+		ids   []string
+		spans = make([][]string, len(hits))
+	)
+	for i, hit := range hits {
+		if hit.Chunk == nil {
+			continue
+		}
+		parent := parents[slices.Index(parentIDs, hit.Chunk.ParentID)]
+		if parent == nil || hit.Chunk.Index >= len(parent.Chunks) {
+			continue
+		}
+		spans[i] = parent.Chunks[max(hit.Chunk.Index-n, 0):min(hit.Chunk.Index+n+1, len(parent.Chunks))]
+		for _, id := range spans[i] {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	chunks, err := r.getDocuments(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, hit := range hits {
+		for _, id := range spans[i] {
+			if chunk := chunks[slices.Index(ids, id)]; chunk != nil {
+				hit.Window = append(hit.Window, chunk)
+			}
+		}
+	}
+	return nil
+}
+
+// WindowText joins the chunks of Window back into the text they cover in the parent document,
+// overlaps removed, or returns Content when there is no window.
+func (doc *ScoredDocument) WindowText() string {
+	if len(doc.Window) == 0 {
+		return doc.Content
+	}
+	var (
+		text []byte
+		end  int
+	)
+	for i, chunk := range doc.Window {
+		if chunk.Chunk == nil {
+			continue
+		}
+		content := chunk.Content
+		if i > 0 {
+			if skip := end - chunk.Chunk.Start; skip > 0 {
+				content = content[min(skip, len(content)):]
+			} else if skip < 0 {
+				// the whitespace trimmed between chunks
+				text = append(text, ' ')
+			}
+		}
+		text = append(text, content...)
+		end = max(end, chunk.Chunk.End)
+	}
+	return string(text)
+}
@@ -0,0 +1,66 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitsark/redis4rag/chunking"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestWindowText(t *testing.T) {
+	content := "咱俩谁跟谁呀。我俩谁跟谁呀！ Redis is fast."
+	doc := &Document{ID: "0", Content: content}
+	chunks := SplitDocument(doc, &chunking.RecursiveCharacter{Size: 10, Overlap: 7})
+	should.Equal(t, 4, len(chunks))
+
+	hit := &ScoredDocument{Document: *chunks[1], Window: chunks}
+	should.Equal(t, content, hit.WindowText())
+	hit.Window = chunks[1:]
+	should.Equal(t, content[chunks[1].Chunk.Start:], hit.WindowText())
+	hit.Window = nil
+	should.Equal(t, chunks[1].Content, hit.WindowText())
+}
+
+func TestRetrievalParentDocument(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_parent_document")
+
+	splitter := &chunking.Sentence{Size: 10}
+	faq := &Document{ID: "faq", Tag: "faq", Content: "如何重置我的密码？怎样修改收货地址？如何重置我的密码呢？今天天气很好。"}
+	should.Nil(t, retriever.StoreChunked(ctx, faq, splitter, nil))
+	should.Nil(t, retriever.StoreChunked(ctx, &Document{ID: "note", Tag: "faq", Content: "如何修改密码？"}, splitter, nil))
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "如何重置我的密码？", "", 2, nil, WithParentDocument())
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "faq", docs[0].ID)
+	should.Equal(t, faq.Content, docs[0].Content)
+	should.Equal(t, []string{"faq#0", "faq#1", "faq#2", "faq#3"}, docs[0].Chunks)
+	should.Equal(t, "note", docs[1].ID)
+
+	docs, err = retriever.Retrieve(ctx, "怎样修改收货地址？", "", 1, nil, WithSentenceWindow(1))
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "faq#1", docs[0].ID)
+	should.Equal(t, &ChunkRef{ParentID: "faq", Index: 1, Start: 27, End: 54}, docs[0].Chunk)
+	should.Equal(t, 3, len(docs[0].Window))
+	should.Equal(t, "如何重置我的密码？怎样修改收货地址？如何重置我的密码呢？", docs[0].WindowText())
+
+	// a shorter version drops the chunks left over
+	faq.Content = "如何重置我的密码？"
+	should.Nil(t, retriever.StoreChunked(ctx, faq, splitter, nil))
+	n, err := retriever.redisCli.Exists(ctx, retriever.key("faq#0"), retriever.key("faq#1"), retriever.key("faq#3")).Result()
+	should.Nil(t, err)
+	should.Equal(t, int64(1), n)
+
+	// deleting the parent deletes its chunks
+	deleted, err := retriever.Delete(ctx, "faq")
+	should.Nil(t, err)
+	should.Equal(t, int64(1), deleted)
+	n, err = retriever.redisCli.Exists(ctx, retriever.key("faq"), retriever.key("faq#0")).Result()
+	should.Nil(t, err)
+	should.Equal(t, int64(0), n)
+}
@@ -0,0 +1,255 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	EmbeddingModelId      = &redis.FieldSchema{FieldName: "$.embedding.model", As: "embedding_model", FieldType: redis.SearchFieldTypeTag}
+	EmbeddingModelVersion = &redis.FieldSchema{FieldName: "$.embedding.version", As: "embedding_version", FieldType: redis.SearchFieldTypeTag}
+)
+
+type (
+	// EmbeddingModel identifies the model that produced a stored vector; it is written to $.embedding.
+	EmbeddingModel struct {
+		ID      string `json:"model"`
+		Version string `json:"version"`
+	}
+
+	// ReembedJob re-embeds every document of an index that was not embedded by Model.
+	// It finds them with a search excluding Model, so it is naturally resumable: running it again,
+	// after a crash or a cancellation, picks up the documents that are still stale.
+	// A model with a different dimension or type needs a new index first, see Reindex.
+	// The named vectors without an Embedder of their own are re-embedded along with the content vector.
+	ReembedJob struct {
+		redisCli  redis.UniversalClient
+		indexName string
+		textPath  string
+		vecPath   string
+		vector    VectorConfig
+		model     EmbeddingModel
+		embedder  BatchEmbedder
+		batchSize int
+		// exclude matches the documents that have no vector to re-embed.
+		exclude string
+		// named are re-embedded from the whole documents, returned as $.
+		named []NamedVector
+
+		// OnProgress, when set, is called after every batch.
+		OnProgress func(ReembedProgress)
+
+		total     atomic.Int64
+		done      atomic.Int64
+		failed    atomic.Int64
+		lastError atomic.Pointer[error]
+	}
+
+	ReembedProgress struct {
+		// Total is the number of stale documents found when the job started.
+		Total  int64
+		Done   int64
+		Failed int64
+		// LastError is the validation error of the last skipped document.
+		LastError error
+	}
+)
+
+func (model EmbeddingModel) filter() string {
+	if len(model.Version) == 0 {
+		return tagClause(EmbeddingModelId.As, model.ID)
+	}
+	return fmt.Sprintf("%s %s", tagClause(EmbeddingModelId.As, model.ID), tagClause(EmbeddingModelVersion.As, model.Version))
+}
+
+// ReembedJob prepares a job moving the retriever's content vectors to model.
+// The parents stored by StoreChunked have no vector and are left alone.
+func (r *Retriever) ReembedJob(model EmbeddingModel, embedder BatchEmbedder) *ReembedJob {
+	job := newReembedJob(r.redisCli, r.indexName, DocContent.FieldName, DocContentVec.FieldName, r.vector, model,
+		batchOf(embedder, r.batcher, r.embedder), r.batchSize)
+	job.exclude = tagClause(DocRole.As, RoleParent)
+	for _, v := range r.named {
+		if v.Embedder == nil {
+			job.named = append(job.named, v)
+		}
+	}
+	return job
+}
+
+// ReembedJob prepares a job moving the cache's query vectors to model.
+func (cache *LLMsCache) ReembedJob(model EmbeddingModel, embedder BatchEmbedder) *ReembedJob {
+	return newReembedJob(cache.redisCli, cache.indexName, QAQuery.FieldName, QAQueryVec.FieldName, cache.vector, model,
+		batchOf(embedder, cache.batcher, cache.embedder), cache.batchSize)
+}
+
+// ReembedJob prepares a job moving the history's content vectors to model.
+func (history *ChatHistory) ReembedJob(model EmbeddingModel, embedder BatchEmbedder) *ReembedJob {
+	return newReembedJob(history.redisCli, history.indexName, ChatMessageContent.FieldName, ChatMessageContentVec.FieldName, history.vector, model,
+		batchOf(embedder, history.batcher, history.embedder), history.batchSize)
+}
+
+func newReembedJob(cli redis.UniversalClient, index, textPath, vecPath string, vector VectorConfig, model EmbeddingModel, embedder BatchEmbedder, batchSize int) *ReembedJob {
+	return &ReembedJob{
+		redisCli:  cli,
+		indexName: index,
+		textPath:  textPath,
+		vecPath:   vecPath,
+		vector:    vector,
+		model:     model,
+		embedder:  embedder,
+		batchSize: batchSize,
+	}
+}
+
+// Run re-embeds stale documents batch by batch until none is left or ctx is done.
+// Embedding errors stop the job; vectors failing validation are skipped and counted as failed.
+func (job *ReembedJob) Run(ctx context.Context) error {
+	if job.embedder == nil {
+		return ErrNoEmbedder
+	}
+	batchSize := job.batchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	query := fmt.Sprintf("-(%s)", job.model.filter())
+	if len(job.exclude) > 0 {
+		query = fmt.Sprintf("%s -(%s)", query, job.exclude)
+	}
+	opts := &redis.FTSearchOptions{
+		Return:         []redis.FTSearchReturn{{FieldName: job.textPath}},
+		DialectVersion: 2,
+		Limit:          batchSize,
+	}
+	if len(job.named) > 0 {
+		opts.Return = []redis.FTSearchReturn{{FieldName: "$"}}
+	}
+	skipped := 0
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// documents that failed stay stale, step over them
+		opts.LimitOffset = skipped
+		res, err := job.redisCli.FTSearchWithArgs(ctx, job.indexName, query, opts).Result()
+		if err != nil {
+			return err
+		}
+		if first {
+			job.total.Store(int64(res.Total))
+		}
+		if len(res.Docs) == 0 {
+			return nil
+		}
+		failed, err := job.reembed(ctx, res.Docs)
+		if err != nil {
+			return err
+		}
+		skipped += failed
+		if job.OnProgress != nil {
+			job.OnProgress(job.Progress())
+		}
+	}
+}
+
+func (job *ReembedJob) Progress() ReembedProgress {
+	progress := ReembedProgress{Total: job.total.Load(), Done: job.done.Load(), Failed: job.failed.Load()}
+	if err := job.lastError.Load(); err != nil {
+		progress.LastError = *err
+	}
+	return progress
+}
+
+func (job *ReembedJob) reembed(ctx context.Context, docs []redis.Document) (failed int, err error) {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Fields[job.textPath]
+	}
+	var documents []*Document
+	if len(job.named) > 0 {
+		documents = make([]*Document, len(docs))
+		for i, doc := range docs {
+			if documents[i], err = parseWhole(doc.Fields["$"]); err != nil {
+				return 0, fmt.Errorf("redis4rag: %s: %w", doc.ID, err)
+			}
+			texts[i] = documents[i].Content
+		}
+	}
+	vecs, err := job.embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	named := make([][][]float64, len(job.named))
+	for n, v := range job.named {
+		for i, doc := range documents {
+			texts[i] = v.text(doc)
+		}
+		if named[n], err = job.embed(ctx, texts); err != nil {
+			return 0, err
+		}
+	}
+
+	pipeline := job.redisCli.Pipeline()
+	for i, doc := range docs {
+		vec, err := job.vector.prepare(vecs[i])
+		vectors := make(map[string]interface{}, len(job.named))
+		for n, v := range job.named {
+			if err != nil {
+				break
+			}
+			var nv []float64
+			if nv, err = v.Config.prepare(named[n][i]); err == nil {
+				vectors[v.Name] = v.Config.jsonValue(nv)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("redis4rag: %s: %w", doc.ID, err)
+			job.lastError.Store(&err)
+			failed++
+			continue
+		}
+		pipeline.JSONSet(ctx, doc.ID, job.vecPath, job.vector.jsonValue(vec))
+		for _, v := range job.named {
+			pipeline.JSONSet(ctx, doc.ID, "$.vectors."+v.Name, vectors[v.Name])
+		}
+		pipeline.JSONSet(ctx, doc.ID, "$.embedding", job.model)
+	}
+	if _, err = pipeline.Exec(ctx); err != nil {
+		return 0, err
+	}
+	job.done.Add(int64(len(docs) - failed))
+	job.failed.Add(int64(failed))
+	return failed, nil
+}
+
+// embed embeds a batch of texts, checking that the embedder returned a vector for each.
+func (job *ReembedJob) embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vecs, err := job.embedder(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(texts) {
+		return nil, fmt.Errorf("redis4rag: batch embedder returned %d vectors for %d texts", len(vecs), len(texts))
+	}
+	return vecs, nil
+}
+
+// parseWhole decodes a whole document returned by a search as $, possibly wrapped in a JSONPath match array.
+func parseWhole(raw string) (*Document, error) {
+	var doc Document
+	if json.Unmarshal([]byte(raw), &doc) == nil {
+		return &doc, nil
+	}
+	var matches []Document
+	if err := json.Unmarshal([]byte(raw), &matches); err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, errors.New("redis4rag: no document returned")
+	}
+	return &matches[0], nil
+}
@@ -0,0 +1,110 @@
+package redis4rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitsark/redis4rag/chunking"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestModelFilter(t *testing.T) {
+	model := EmbeddingModel{ID: "text-embedding-3-small", Version: "2024.01"}
+	should.Equal(t, `@embedding_model:{text\-embedding\-3\-small} @embedding_version:{2024\.01}`, model.filter())
+	should.Equal(t, `@embedding_model:{bge\-m3}`, EmbeddingModel{ID: "bge-m3"}.filter())
+
+	should.Equal(t, "*", newSearchOptions().filter(""))
+	should.Equal(t, `@tag:{a} @embedding_model:{bge\-m3}`,
+		newSearchOptions(WithModelVersion(EmbeddingModel{ID: "bge-m3"})).filter("@tag:{a}"))
+}
+
+func TestReembedJob(t *testing.T) {
+	ctx := context.Background()
+
+	v1 := EmbeddingModel{ID: "hash", Version: "1"}
+	v2 := EmbeddingModel{ID: "hash", Version: "2"}
+	retriever := newTestRetriever(t, "reembed_job",
+		WithEmbedder(NewHashEmbedder(64).Embed),
+		WithEmbeddingModel(v1),
+		WithVectorConfig(VectorConfig{Dim: 64}),
+		WithBatchSize(2))
+
+	docs := []*Document{}
+	for _, content := range []string{"咱俩谁跟谁呀。", "我俩谁跟谁呀。", "咱俩关系不错呀。", "咱俩关系很好。", "今天天气不错"} {
+		docs = append(docs, &Document{ID: content, Content: content})
+	}
+	should.Nil(t, retriever.StoreBatch(ctx, docs, nil))
+	time.Sleep(100 * time.Millisecond)
+
+	found, err := retriever.Retrieve(ctx, "咱俩谁跟谁呀。", "", 5, nil, WithModelVersion(v2))
+	should.Nil(t, err)
+	should.Empty(t, found)
+
+	var reports []ReembedProgress
+	job := retriever.ReembedJob(v2, NewHashEmbedder(64).EmbedBatch)
+	job.OnProgress = func(progress ReembedProgress) { reports = append(reports, progress) }
+	should.Nil(t, job.Run(ctx))
+	should.Equal(t, ReembedProgress{Total: 5, Done: 5}, job.Progress())
+	should.Equal(t, 3, len(reports))
+
+	found, err = retriever.Retrieve(ctx, "咱俩谁跟谁呀。", "", 5, nil, WithModelVersion(v2))
+	should.Nil(t, err)
+	should.Equal(t, 5, len(found))
+
+	// nothing is left to do on a second run
+	job = retriever.ReembedJob(v2, NewHashEmbedder(64).EmbedBatch)
+	should.Nil(t, job.Run(ctx))
+	should.Equal(t, ReembedProgress{}, job.Progress())
+
+	// chunks are re-embedded, their parent is left without a vector
+	parent := &Document{ID: "faq", Content: "如何重置我的密码？怎样修改收货地址？"}
+	should.Nil(t, retriever.StoreChunked(ctx, parent, &chunking.Sentence{Size: 10}, nil))
+	time.Sleep(100 * time.Millisecond)
+	job = retriever.ReembedJob(v2, NewHashEmbedder(64).EmbedBatch)
+	should.Nil(t, job.Run(ctx))
+	should.Equal(t, ReembedProgress{Total: 2, Done: 2}, job.Progress())
+	vec, err := retriever.redisCli.JSONGet(ctx, retriever.key("faq"), DocContentVec.FieldName).Result()
+	should.Nil(t, err)
+	should.Equal(t, "[]", vec)
+	found, err = retriever.Retrieve(ctx, "如何重置我的密码？", "", 10, nil, WithModelVersion(v2))
+	should.Nil(t, err)
+	should.Equal(t, 7, len(found))
+	for _, doc := range found {
+		should.NotEqual(t, "faq", doc.ID)
+	}
+}
+
+func TestReembedJobNamedVectors(t *testing.T) {
+	ctx := context.Background()
+
+	// v2 is told apart from v1 by its vectors pointing the other way
+	v1 := EmbeddingModel{ID: "hash", Version: "1"}
+	v2 := EmbeddingModel{ID: "hash", Version: "2"}
+	negated := func(ctx context.Context, text string) ([]float64, error) {
+		vec, err := NewHashEmbedder(64).Embed(ctx, text)
+		for i := range vec {
+			vec[i] = -vec[i]
+		}
+		return vec, err
+	}
+	retriever := newTestRetriever(t, "reembed_job_named",
+		WithEmbedder(NewHashEmbedder(64).Embed),
+		WithEmbeddingModel(v1),
+		WithVectorConfig(VectorConfig{Dim: 64}),
+		WithNamedVector(NamedVector{Name: "title", Config: VectorConfig{Dim: 64}, Text: func(doc *Document) string { return doc.Tag }}))
+
+	should.Nil(t, retriever.Store(ctx, &Document{ID: "0", Tag: "密码", Content: "如何重置我的密码？"}, nil))
+	time.Sleep(100 * time.Millisecond)
+
+	job := retriever.ReembedJob(v2, Embedder(negated).Batch())
+	should.Nil(t, job.Run(ctx))
+	should.Equal(t, ReembedProgress{Total: 1, Done: 1}, job.Progress())
+
+	for field, query := range map[string]string{"": "如何重置我的密码？", "title": "密码"} {
+		found, err := retriever.Retrieve(ctx, query, "", 1, negated, WithVectorField(field), WithModelVersion(v2))
+		should.Nil(t, err)
+		should.Equal(t, 1, len(found))
+		should.InDelta(t, 1, found[0].Similarity, 1e-3)
+	}
+}
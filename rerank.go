@@ -0,0 +1,186 @@
+package redis4rag
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// rerankOverfetch is the default factor of WithReranker.
+const rerankOverfetch = 4
+
+type (
+	// Reranker reorders the candidates of a search for query, best first, setting their ScoredDocument.Rerank.
+	// It may drop candidates but must not add any.
+	Reranker interface {
+		Rerank(ctx context.Context, query string, docs []*ScoredDocument) ([]*ScoredDocument, error)
+	}
+
+	// LexicalReranker is a dependency free Reranker scoring candidates by BM25 against the query,
+	// term statistics being taken from the candidates themselves. Terms are lowercase words, and
+	// character bigrams for CJK text. Candidates with equal scores keep their order.
+	LexicalReranker struct {
+		// K1 defaults to 1.2 and B to 0.75.
+		K1 float64
+		B  float64
+	}
+
+	// TEIReranker calls a Text Embeddings Inference compatible POST {BaseURL}/rerank endpoint.
+	TEIReranker struct {
+		opts HTTPEmbedderOptions
+	}
+
+	// CohereReranker calls a Cohere compatible POST {BaseURL}/rerank endpoint,
+	// as served by Cohere, Jina, vLLM and others.
+	CohereReranker struct {
+		opts HTTPEmbedderOptions
+	}
+)
+
+// Rerank is a Reranker.
+func (l *LexicalReranker) Rerank(_ context.Context, query string, docs []*ScoredDocument) ([]*ScoredDocument, error) {
+	var (
+		k1     = cmp.Or(l.K1, 1.2)
+		b      = cmp.Or(l.B, 0.75)
+		terms  = lexicalTerms(query)
+		counts = make([]map[string]int, len(docs))
+		df     = map[string]int{}
+		avgLen float64
+	)
+	for i, doc := range docs {
+		counts[i] = map[string]int{}
+		docTerms := lexicalTerms(doc.Content)
+		for _, term := range docTerms {
+			if counts[i][term]++; counts[i][term] == 1 {
+				df[term]++
+			}
+		}
+		avgLen += float64(len(docTerms)) / float64(len(docs))
+	}
+	scores := make([]float64, len(docs))
+	for i, count := range counts {
+		var n int
+		for _, c := range count {
+			n += c
+		}
+		seen := map[string]bool{}
+		for _, term := range terms {
+			if seen[term] || count[term] == 0 {
+				continue
+			}
+			seen[term] = true
+			idf := math.Log(1 + (float64(len(docs)-df[term])+0.5)/(float64(df[term])+0.5))
+			tf := float64(count[term])
+			scores[i] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(n)/avgLen))
+		}
+	}
+	return sortByRerank(docs, scores), nil
+}
+
+// lexicalTerms are the words of text, CJK runs contributing their character bigrams.
+func lexicalTerms(text string) (terms []string) {
+	for _, run := range segment(text) {
+		runes := []rune(run)
+		if !isCJK(runes[0]) || len(runes) == 1 {
+			terms = append(terms, run)
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			terms = append(terms, string(runes[i:i+2]))
+		}
+	}
+	return
+}
+
+// sortByRerank sets the Rerank of docs to scores and sorts them by it, highest first.
+func sortByRerank(docs []*ScoredDocument, scores []float64) []*ScoredDocument {
+	for i, doc := range docs {
+		doc.Rerank = &scores[i]
+	}
+	slices.SortStableFunc(docs, func(a, b *ScoredDocument) int { return cmp.Compare(*b.Rerank, *a.Rerank) })
+	return docs
+}
+
+// NewTEIReranker makes a TEIReranker, BaseURL defaulting to http://localhost:8080.
+// Only BaseURL, APIKey, Headers, Timeout and Client of opts apply.
+func NewTEIReranker(opts HTTPEmbedderOptions) *TEIReranker {
+	opts.BaseURL = cmp.Or(opts.BaseURL, "http://localhost:8080")
+	return &TEIReranker{opts: opts.withDefaults()}
+}
+
+// Rerank is a Reranker.
+func (t *TEIReranker) Rerank(ctx context.Context, query string, docs []*ScoredDocument) ([]*ScoredDocument, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+	req := struct {
+		Query string   `json:"query"`
+		Texts []string `json:"texts"`
+	}{query, contents(docs)}
+	var res []rerankResult
+	if err := t.opts.post(ctx, "/rerank", &req, &res); err != nil {
+		return nil, err
+	}
+	return rankBy(docs, res)
+}
+
+// NewCohereReranker makes a CohereReranker, BaseURL defaulting to https://api.cohere.com/v2.
+// Only BaseURL, Model, APIKey, Headers, Timeout and Client of opts apply.
+func NewCohereReranker(opts HTTPEmbedderOptions) *CohereReranker {
+	opts.BaseURL = cmp.Or(opts.BaseURL, "https://api.cohere.com/v2")
+	return &CohereReranker{opts: opts.withDefaults()}
+}
+
+// Rerank is a Reranker.
+func (c *CohereReranker) Rerank(ctx context.Context, query string, docs []*ScoredDocument) ([]*ScoredDocument, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+	req := struct {
+		Model     string   `json:"model,omitempty"`
+		Query     string   `json:"query"`
+		Documents []string `json:"documents"`
+	}{c.opts.Model, query, contents(docs)}
+	var res struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := c.opts.post(ctx, "/rerank", &req, &res); err != nil {
+		return nil, err
+	}
+	results := make([]rerankResult, len(res.Results))
+	for i, result := range res.Results {
+		results[i] = rerankResult{result.Index, result.RelevanceScore}
+	}
+	return rankBy(docs, results)
+}
+
+type rerankResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// rankBy orders the docs picked by the results of a rerank endpoint by their scores.
+func rankBy(docs []*ScoredDocument, results []rerankResult) ([]*ScoredDocument, error) {
+	ranked := make([]*ScoredDocument, len(results))
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		if result.Index < 0 || result.Index >= len(docs) {
+			return nil, fmt.Errorf("redis4rag: rerank endpoint returned index %d for %d documents", result.Index, len(docs))
+		}
+		ranked[i], scores[i] = docs[result.Index], result.Score
+	}
+	return sortByRerank(ranked, scores), nil
+}
+
+func contents(docs []*ScoredDocument) []string {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Content
+	}
+	return texts
+}
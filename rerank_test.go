@@ -0,0 +1,166 @@
+package redis4rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func scoredDocs(contents ...string) []*ScoredDocument {
+	docs := make([]*ScoredDocument, len(contents))
+	for i, content := range contents {
+		docs[i] = &ScoredDocument{Document: Document{ID: string(rune('a' + i)), Content: content}}
+	}
+	return docs
+}
+
+func rerankedIDs(docs []*ScoredDocument) (ids []string) {
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return
+}
+
+func TestLexicalReranker(t *testing.T) {
+	docs, err := (&LexicalReranker{}).Rerank(context.Background(), "reset the router password", scoredDocs(
+		"How do I change the Wi-Fi name?",
+		"Hold the reset button for ten seconds",
+		"To reset the router password, open the admin page",
+		"Nothing in common",
+	))
+	should.Nil(t, err)
+	should.Equal(t, []string{"c", "b", "a", "d"}, rerankedIDs(docs))
+	should.Greater(t, *docs[0].Rerank, *docs[1].Rerank)
+	should.Equal(t, 0.0, *docs[3].Rerank)
+
+	docs, err = (&LexicalReranker{}).Rerank(context.Background(), "重置密码", scoredDocs("如何修改用户名", "忘记密码怎么重置"))
+	should.Nil(t, err)
+	should.Equal(t, []string{"b", "a"}, rerankedIDs(docs))
+	should.Equal(t, []string{"重置", "置密", "密码", "redis", "7"}, lexicalTerms("重置密码，Redis 7"))
+
+	docs, err = (&LexicalReranker{}).Rerank(context.Background(), "anything", nil)
+	should.Nil(t, err)
+	should.Empty(t, docs)
+}
+
+func TestTEIReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		should.Equal(t, "/rerank", r.URL.Path)
+		var req struct {
+			Query string   `json:"query"`
+			Texts []string `json:"texts"`
+		}
+		should.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.Query == "bad" {
+			http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		if req.Query == "out of range" {
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"index": len(req.Texts), "score": 1}})
+			return
+		}
+		should.Equal(t, []string{"x", "yy", "zzz"}, req.Texts)
+		// TEI answers sorted by score
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"index": 2, "score": 0.9},
+			{"index": 0, "score": 0.5},
+			{"index": 1, "score": 0.1},
+		})
+	}))
+	defer server.Close()
+
+	reranker := NewTEIReranker(HTTPEmbedderOptions{BaseURL: server.URL + "/"})
+	docs, err := reranker.Rerank(context.Background(), "query", scoredDocs("x", "yy", "zzz"))
+	should.Nil(t, err)
+	should.Equal(t, []string{"c", "a", "b"}, rerankedIDs(docs))
+	should.Equal(t, 0.9, *docs[0].Rerank)
+
+	_, err = reranker.Rerank(context.Background(), "bad", scoredDocs("x"))
+	var httpErr *HTTPError
+	should.True(t, errors.As(err, &httpErr))
+	should.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+
+	_, err = reranker.Rerank(context.Background(), "out of range", scoredDocs("x"))
+	should.ErrorContains(t, err, "index 1")
+
+	docs, err = reranker.Rerank(context.Background(), "no request", nil)
+	should.Nil(t, err)
+	should.Empty(t, docs)
+}
+
+func TestCohereReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		should.Equal(t, "/v2/rerank", r.URL.Path)
+		should.Equal(t, "Bearer co-test", r.Header.Get("Authorization"))
+		var req struct {
+			Model     string   `json:"model"`
+			Query     string   `json:"query"`
+			Documents []string `json:"documents"`
+		}
+		should.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		should.Equal(t, "rerank-v3.5", req.Model)
+		should.Equal(t, "query", req.Query)
+		should.Equal(t, []string{"x", "yy"}, req.Documents)
+		// out of order on purpose
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"index": 0, "relevance_score": 0.2},
+			{"index": 1, "relevance_score": 0.7},
+		}})
+	}))
+	defer server.Close()
+
+	reranker := NewCohereReranker(HTTPEmbedderOptions{BaseURL: server.URL + "/v2", Model: "rerank-v3.5", APIKey: "co-test"})
+	docs, err := reranker.Rerank(context.Background(), "query", scoredDocs("x", "yy"))
+	should.Nil(t, err)
+	should.Equal(t, []string{"b", "a"}, rerankedIDs(docs))
+	should.Equal(t, 0.7, *docs[0].Rerank)
+	should.Equal(t, 0.2, *docs[1].Rerank)
+}
+
+// reverseReranker reverses the candidates, recording how many it got.
+type reverseReranker struct {
+	candidates int
+}
+
+func (r *reverseReranker) Rerank(_ context.Context, _ string, docs []*ScoredDocument) ([]*ScoredDocument, error) {
+	r.candidates = len(docs)
+	scores := make([]float64, len(docs))
+	for i := range docs {
+		scores[i] = float64(i)
+	}
+	return sortByRerank(docs, scores), nil
+}
+
+func TestRetrievalReranker(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_reranker")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "How do I reset my password?"},
+		{Tag: "faq", ID: "1", Content: "How can I reset my password?"},
+		{Tag: "faq", ID: "2", Content: "Password reset link expired"},
+		{Tag: "faq", ID: "3", Content: "Change the account email"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	reranker := &reverseReranker{}
+	docs, err := retriever.Retrieve(ctx, "reset my password", "faq", 1, nil, WithReranker(reranker, 3))
+	should.Nil(t, err)
+	should.Equal(t, 3, reranker.candidates)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "2", docs[0].ID)
+	should.Equal(t, 2.0, *docs[0].Rerank)
+	should.Greater(t, docs[0].Similarity, 0.0)
+
+	docs, err = retriever.Retrieve(ctx, "link expired", "faq", 2, nil, WithReranker(&LexicalReranker{}, 0))
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "2", docs[0].ID)
+}
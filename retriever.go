@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/redis/go-redis/v9"
@@ -16,6 +17,10 @@ var (
 		DocContent,
 		DocPayload,
 		DocContentVec,
+		EmbeddingModelId,
+		EmbeddingModelVersion,
+		DocRole,
+		DocTerms,
 	}
 
 	DocumentDefaultReturn = []redis.FTSearchReturn{
@@ -23,27 +28,48 @@ var (
 		{FieldName: DocTag.FieldName},
 		{FieldName: DocContent.FieldName},
 		{FieldName: DocPayload.FieldName},
+		{FieldName: DocMetadata},
+		{FieldName: DocChunk},
 	}
 
 	DocId         = &redis.FieldSchema{FieldName: "$.id", As: "id", FieldType: redis.SearchFieldTypeText, NoIndex: true}
 	DocTag        = &redis.FieldSchema{FieldName: "$.tag", As: "tag", FieldType: redis.SearchFieldTypeTag, Separator: ","}
 	DocContent    = &redis.FieldSchema{FieldName: "$.content", As: "content", FieldType: redis.SearchFieldTypeText}
 	DocPayload    = &redis.FieldSchema{FieldName: "$.payload", As: "payload", FieldType: redis.SearchFieldTypeText, NoIndex: true}
-	DocContentVec = &redis.FieldSchema{FieldName: "$.content_vec", As: "content_vec", FieldType: redis.SearchFieldTypeVector,
-		VectorArgs: &redis.FTVectorArgs{
-			FlatOptions: &redis.FTFlatOptions{
-				Type:           "FLOAT64",
-				Dim:            1024,
-				DistanceMetric: "COSINE",
-			},
-		}}
+	DocContentVec = DefaultVectorConfig.Field("$.content_vec", "content_vec")
+	// DocRole is RoleParent on the documents stored by StoreChunked, which have no vector of their own.
+	DocRole = &redis.FieldSchema{FieldName: "$.role", As: "role", FieldType: redis.SearchFieldTypeTag}
+	// DocTerms are the terms of the content, see lexicalTerms, searched by WithHybrid: the default
+	// tokenizer keeps CJK runs whole, so $.content alone never matches part of a CJK sentence.
+	DocTerms = &redis.FieldSchema{FieldName: "$.terms", As: "terms", FieldType: redis.SearchFieldTypeText}
 )
 
+// rangePageSize is the page size of range searches that are not capped at K.
+const rangePageSize = 1000
+
 type (
+	// NamedVector is an extra vector of a document, stored at $.vectors.{Name} and indexed as vec_{Name}.
+	// Name may only contain letters, digits and underscores.
+	NamedVector struct {
+		Name   string
+		Config VectorConfig
+		// Embedder defaults to the one Store or Retrieve is called with.
+		Embedder Embedder
+		// Text picks the text to embed, the document content by default.
+		Text func(*Document) string
+	}
+
 	Retriever struct {
 		indexName string
 		docPrefix string
-		redisCli  *redis.Client
+		redisCli  redis.UniversalClient
+		embedder  Embedder
+		batcher   BatchEmbedder
+		batchSize int
+		vector    VectorConfig
+		model     EmbeddingModel
+		named     []NamedVector
+		metadata  []MetadataField
 	}
 
 	Document struct {
@@ -51,65 +77,386 @@ type (
 		Tag     string `json:"tag"`
 		Content string `json:"content"`
 		Payload string `json:"payload"`
+		// Metadata entries declared WithMetadataField are indexed, others are only stored.
+		Metadata map[string]any `json:"metadata,omitempty"`
+		// Chunk is set on the chunks made by SplitDocument.
+		Chunk *ChunkRef `json:"chunk,omitempty"`
+		// Chunks are the IDs of the chunks of a document stored by StoreChunked, in order.
+		Chunks []string `json:"chunks,omitempty"`
 	}
 )
 
+// DocumentSchema generates the retriever schema with cfg as its vector field.
+func (cfg VectorConfig) DocumentSchema() []*redis.FieldSchema {
+	return []*redis.FieldSchema{
+		DocId,
+		DocTag,
+		DocContent,
+		DocPayload,
+		cfg.Field(DocContentVec.FieldName, DocContentVec.As),
+		EmbeddingModelId,
+		EmbeddingModelVersion,
+		DocRole,
+		DocTerms,
+	}
+}
+
+func NewRetriever(ctx context.Context, opts ...Option) (*Retriever, error) {
+	o := newOptions("idx:documents", "doc:documents", VectorConfig.DocumentSchema, opts...)
+	if err := o.init(ctx); err != nil {
+		return nil, err
+	}
+	return &Retriever{
+		indexName: o.indexName,
+		docPrefix: o.docPrefix,
+		redisCli:  o.redisCli,
+		embedder:  o.embedder,
+		batcher:   o.batcher,
+		batchSize: o.batchSize,
+		vector:    *o.vector,
+		model:     o.model,
+		named:     o.named,
+		metadata:  o.metadata,
+	}, nil
+}
+
 func (r *Retriever) Store(ctx context.Context, doc *Document, embedder Embedder) (err error) {
 	var vec []float64
-	vec, err = embedder(ctx, doc.Content)
+	vec, err = embed(ctx, doc.Content, embedder, r.embedder)
 	if err != nil {
 		return
 	}
-
-	var jsonData []byte
-	jsonData, err = json.Marshal(doc)
-	if err != nil {
+	var named []map[string]interface{}
+	if named, err = r.embedNamed(ctx, []*Document{doc}, cmpEmbedder(embedder, r.embedder).Batch()); err != nil {
 		return
 	}
 
-	// document key pattern: {Retriever.DocPrefix}:{Document.ID}
 	pipeline := r.redisCli.Pipeline()
-	key := fmt.Sprintf("%s:%s", r.docPrefix, doc.ID)
-	pipeline.JSONSet(ctx, key, "$", string(jsonData))
-	pipeline.JSONSet(ctx, key, DocContentVec.FieldName, vec)
+	if err = r.write(ctx, pipeline, doc, vec, named[0]); err != nil {
+		return
+	}
 	_, err = pipeline.Exec(ctx)
 	return err
 }
 
-func (r *Retriever) Retrieve(ctx context.Context, content string, tag string, topK int, embedder Embedder) (docs []*Document, err error) {
+// StoreBatch embeds and stores docs in chunks of the configured batch size, one pipeline per chunk.
+func (r *Retriever) StoreBatch(ctx context.Context, docs []*Document, embedder BatchEmbedder) error {
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.Content
+	}
+	batcher := batchOf(embedder, r.batcher, r.embedder)
+	return forEachBatch(ctx, texts, r.batchSize, batcher, func(lo, hi int, vecs [][]float64) error {
+		named, err := r.embedNamed(ctx, docs[lo:hi], batcher)
+		if err != nil {
+			return err
+		}
+		pipeline := r.redisCli.Pipeline()
+		for i, doc := range docs[lo:hi] {
+			if err = r.write(ctx, pipeline, doc, vecs[i], named[i]); err != nil {
+				return err
+			}
+		}
+		_, err = pipeline.Exec(ctx)
+		return err
+	})
+}
+
+func (r *Retriever) write(ctx context.Context, pipeline redis.Pipeliner, doc *Document, vec []float64, named map[string]interface{}) error {
+	vec, err := r.vector.prepare(vec)
+	if err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	terms, err := json.Marshal(lexicalText(doc.Content))
+	if err != nil {
+		return err
+	}
+	key := r.key(doc.ID)
+	pipeline.JSONSet(ctx, key, "$", string(jsonData))
+	pipeline.JSONSet(ctx, key, DocContentVec.FieldName, r.vector.jsonValue(vec))
+	pipeline.JSONSet(ctx, key, DocTerms.FieldName, string(terms))
+	if len(r.model.ID) > 0 {
+		pipeline.JSONSet(ctx, key, "$.embedding", r.model)
+	}
+	if len(named) > 0 {
+		pipeline.JSONSet(ctx, key, "$.vectors", named)
+	}
+	return nil
+}
+
+// embedNamed embeds the named vectors of docs, falling back to embedder for those without their own.
+func (r *Retriever) embedNamed(ctx context.Context, docs []*Document, embedder BatchEmbedder) ([]map[string]interface{}, error) {
+	return r.embedVectors(ctx, r.named, docs, embedder)
+}
+
+// embedVectors embeds the given named vectors of docs.
+func (r *Retriever) embedVectors(ctx context.Context, vectors []NamedVector, docs []*Document, embedder BatchEmbedder) ([]map[string]interface{}, error) {
+	named := make([]map[string]interface{}, len(docs))
+	if len(vectors) == 0 {
+		return named, nil
+	}
+	for i := range named {
+		named[i] = make(map[string]interface{}, len(vectors))
+	}
+	for _, v := range vectors {
+		texts := make([]string, len(docs))
+		for i, doc := range docs {
+			texts[i] = v.text(doc)
+		}
+		batcher := batchOf(nil, nil, v.Embedder)
+		if batcher == nil {
+			batcher = embedder
+		}
+		err := forEachBatch(ctx, texts, len(texts), batcher, func(_, _ int, vecs [][]float64) (err error) {
+			for i, vec := range vecs {
+				if vec, err = v.Config.prepare(vec); err != nil {
+					return
+				}
+				named[i][v.Name] = v.Config.jsonValue(vec)
+			}
+			return
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return named, nil
+}
+
+// vectorField resolves the field, config and embedder of the vector named by a search, the content vector for "".
+func (r *Retriever) vectorField(name string, embedder Embedder) (as string, cfg VectorConfig, _ Embedder, err error) {
+	if len(name) == 0 {
+		return DocContentVec.As, r.vector, cmpEmbedder(embedder, r.embedder), nil
+	}
+	for _, v := range r.named {
+		if v.Name == name {
+			return v.as(), v.Config, cmpEmbedder(v.Embedder, embedder, r.embedder), nil
+		}
+	}
+	return "", cfg, nil, fmt.Errorf("redis4rag: unknown vector field %q", name)
+}
+
+func (v NamedVector) as() string {
+	return "vec_" + v.Name
+}
+
+func (v NamedVector) field() *redis.FieldSchema {
+	return v.Config.Field("$.vectors."+v.Name, v.as())
+}
+
+func (v NamedVector) text(doc *Document) string {
+	if v.Text != nil {
+		return v.Text(doc)
+	}
+	return doc.Content
+}
+
+func validVectorName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		if !(c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Retrieve returns the topK documents nearest to content, closest first. WithMaxDistance and
+// WithMinSimilarity drop the ones too far away; WithVectorRange returns every document within that
+// radius instead, capped at topK when topK > 0. WithHybrid fuses it with a full-text search on content.
+// WithParentDocument and WithSentenceWindow expand the chunks stored by StoreChunked.
+func (r *Retriever) Retrieve(ctx context.Context, content string, tag string, topK int, embedder Embedder, searchOpts ...SearchOption) (docs []*ScoredDocument, err error) {
+	so := newSearchOptions(searchOpts...)
+	var (
+		field string
+		cfg   VectorConfig
+	)
+	if field, cfg, embedder, err = r.vectorField(so.vectorField, embedder); err != nil {
+		return
+	}
 	var vec []float64
-	vec, err = embedder(ctx, content)
+	vec, err = embed(ctx, content, embedder, nil)
 	if err != nil {
 		return
 	}
+	if vec, err = cfg.prepare(vec); err != nil {
+		return
+	}
+
+	var tagFilter string
+	if len(tag) > 0 {
+		tagFilter = tagClause(DocTag.As, strings.Split(tag, ",")...)
+	}
+	filter := so.filter(tagFilter)
+	k := topK
+	if so.parents {
+		k *= parentOverfetch
+	}
+	fetch := k
+	switch {
+	case so.mmr != nil:
+		fetch = so.mmr.candidates(k)
+	case so.reranker != nil:
+		fetch = k * so.rerankFactor
+	}
+	if so.fusion != nil {
+		docs, err = r.hybrid(ctx, content, filter, field, cfg, vec, fetch, so)
+	} else {
+		docs, err = r.vectorSearch(ctx, filter, field, cfg, vec, fetch, so)
+	}
+	if err == nil && so.mmr != nil {
+		docs = so.mmr.rerank(docs, k)
+	}
+	if err == nil && so.reranker != nil {
+		if docs, err = so.reranker.Rerank(ctx, content, docs); err == nil && k > 0 && len(docs) > k {
+			docs = docs[:k]
+		}
+	}
+	switch {
+	case err != nil:
+		return nil, err
+	case so.parents:
+		return r.parents(ctx, docs, topK)
+	case so.window > 0:
+		err = r.windows(ctx, docs, so.window)
+	}
+	return
+}
 
+func (r *Retriever) vectorSearch(ctx context.Context, filter, field string, cfg VectorConfig, vec []float64, topK int, so *searchOptions) (docs []*ScoredDocument, err error) {
 	opts := &redis.FTSearchOptions{
-		Return:         DocumentDefaultReturn,
+		Return:         withScore(DocumentDefaultReturn),
 		DialectVersion: 2,
-		Params:         map[string]interface{}{"vec": []byte(vector2string(vec))},
+		Limit:          topK,
+		Params:         map[string]interface{}{"vec": []byte(vector2string(vec, cfg.Type))},
 		SortBy: []redis.FTSearchSortBy{
-			{FieldName: "score", Asc: true},
+			{FieldName: scoreField, Asc: true},
 		},
 	}
-
-	filter := "*"
-	if len(tag) > 0 {
-		filter = fmt.Sprintf("@%s:{%s}", DocTag.As, strings.ReplaceAll(tag, ",", "|"))
+	if so.mmr != nil {
+		opts.Return = append(opts.Return, redis.FTSearchReturn{FieldName: field})
 	}
-	query := fmt.Sprintf("(%s)=>[KNN %d @%s $vec AS %s]", filter, topK, DocContentVec.As, "score")
-	cmd := r.redisCli.FTSearchWithArgs(ctx, r.indexName, query, opts)
-	if res, err := cmd.Result(); err != nil {
-		return docs, err
-	} else if res.Total > 0 {
+	radius, bounded := so.radius(cfg)
+	query := fmt.Sprintf("(%s)=>[%s]", filter, cfg.knn(topK, field, scoreField))
+	if so.vectorRange {
+		if !bounded {
+			return nil, ErrNoRadius
+		}
+		opts.Params["radius"] = min(radius, math.MaxFloat64)
+		if topK <= 0 {
+			opts.Limit = rangePageSize
+		}
+		query = cfg.vectorRange(field, scoreField)
+		if filter != "*" {
+			query = fmt.Sprintf("(%s) %s", filter, query)
+		}
+	}
+	for {
+		res, err := r.redisCli.FTSearchWithArgs(ctx, r.indexName, query, opts).Result()
+		if err != nil {
+			return docs, err
+		}
 		for _, raw := range res.Docs {
-			doc := parseDocument(&raw)
+			score := cfg.score(&raw)
+			if !so.accept(cfg, score.Distance) {
+				continue
+			}
+			parsed, err := parseDocument(&raw, r.metadata)
+			if err != nil {
+				return docs, err
+			}
+			doc := &ScoredDocument{Document: *parsed, Score: score}
+			if so.mmr != nil {
+				doc.vector = parseVector(raw.Fields[field])
+			}
 			docs = append(docs, doc)
 		}
+		// an uncapped range search pages through every match
+		opts.LimitOffset += len(res.Docs)
+		if !so.vectorRange || topK > 0 || len(res.Docs) == 0 || opts.LimitOffset >= res.Total {
+			return docs, nil
+		}
 	}
-	return
 }
 
-func parseDocument(res *redis.Document) *Document {
+// document key pattern: {Retriever.DocPrefix}:{{Document.ID}}, hash tagged on the ID
+func (r *Retriever) key(id string) string {
+	return fmt.Sprintf("%s:%s", r.docPrefix, hashTag(id))
+}
+
+// MigrateKeys moves the documents stored as {DocPrefix}:{ID} by versions before keys were hash
+// tagged to {DocPrefix}:{{ID}}, and returns how many it moved.
+// Until it has run, those documents are searched next to any newer copy but Get, Update, Exists
+// and Delete do not see them; run it once after upgrading, it is safe on a live index.
+func (r *Retriever) MigrateKeys(ctx context.Context) (int64, error) {
+	return migrateKeys(ctx, r.redisCli, r.docPrefix, func(value json.RawMessage) (string, bool) {
+		var doc struct {
+			ID *string `json:"id"`
+		}
+		if json.Unmarshal(value, &doc) != nil || doc.ID == nil {
+			return "", false
+		}
+		return r.key(*doc.ID), true
+	})
+}
+
+// documentPaths are the paths of a Document in its JSON, its vectors left out.
+var documentPaths = []string{DocId.FieldName, DocTag.FieldName, DocContent.FieldName, DocPayload.FieldName, DocMetadata, DocChunk, DocChunks}
+
+// getDocuments reads the documents of ids in one pipeline, nil for the missing ones.
+func (r *Retriever) getDocuments(ctx context.Context, ids []string) ([]*Document, error) {
+	pipeline := r.redisCli.Pipeline()
+	cmds := make([]*redis.JSONCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipeline.JSONGet(ctx, r.key(id), documentPaths...)
+	}
+	if _, err := pipeline.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	docs := make([]*Document, len(ids))
+	for i, cmd := range cmds {
+		val := cmd.Val()
+		if len(val) == 0 {
+			continue
+		}
+		// a multi path JSON.GET replies {"$.id":["0"],...}
+		var matches map[string][]json.RawMessage
+		if err := json.Unmarshal([]byte(val), &matches); err != nil {
+			return nil, err
+		}
+		fields := make(map[string]json.RawMessage, len(matches))
+		for path, match := range matches {
+			if len(match) > 0 {
+				fields[strings.TrimPrefix(path, "$.")] = match[0]
+			}
+		}
+		var doc struct {
+			Document
+			Metadata json.RawMessage `json:"metadata"`
+		}
+		data, _ := json.Marshal(fields)
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		docs[i] = &doc.Document
+		if len(doc.Metadata) > 0 {
+			var err error
+			if docs[i].Metadata, err = parseMetadata(string(doc.Metadata), r.metadata); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return docs, nil
+}
+
+// parseDocument decodes a document returned by a search, failing like getDocuments on metadata it cannot decode.
+func parseDocument(res *redis.Document, metadata []MetadataField) (_ *Document, err error) {
 	var doc Document
 	for key, val := range res.Fields {
 		switch key {
@@ -121,7 +468,16 @@ func parseDocument(res *redis.Document) *Document {
 			doc.Tag = val
 		case DocPayload.FieldName:
 			doc.Payload = val
+		case DocMetadata:
+			if doc.Metadata, err = parseMetadata(val, metadata); err != nil {
+				return
+			}
+		case DocChunk:
+			var ref ChunkRef
+			if json.Unmarshal([]byte(firstMatch(val)), &ref) == nil {
+				doc.Chunk = &ref
+			}
 		}
 	}
-	return &doc
+	return &doc, nil
 }
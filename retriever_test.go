@@ -13,6 +13,48 @@ import (
 	should "github.com/stretchr/testify/assert"
 )
 
+// testRedis connects to REDIS_URL, localhost:6379 by default, skipping t when Redis is unreachable.
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	redisCli := redis.NewClient(&redis.Options{
+		Addr:     cmp.Or(os.Getenv("REDIS_URL"), "localhost:6379"),
+		Protocol: 2,
+	})
+	if err := redisCli.Ping(context.Background()).Err(); err != nil {
+		redisCli.Close()
+		t.Skipf("redis unreachable: %v", err)
+	}
+	t.Cleanup(func() { redisCli.Close() })
+	return redisCli
+}
+
+// newTestRetriever makes a Retriever over a fresh index idx:test_{name} of doc:test_{name} documents,
+// embedded by a 256 dimension HashEmbedder unless opts say otherwise, and drops them when t ends.
+func newTestRetriever(t *testing.T, name string, opts ...Option) *Retriever {
+	t.Helper()
+	ctx := context.Background()
+	redisCli := testRedis(t)
+	indexname := "idx:test_" + name
+	redisCli.FTDropIndexWithArgs(ctx, indexname, &redis.FTDropIndexOptions{DeleteDocs: true})
+	t.Cleanup(func() {
+		redisCli.FTDropIndexWithArgs(ctx, indexname, &redis.FTDropIndexOptions{DeleteDocs: true})
+	})
+
+	opts = append([]Option{
+		WithIndexName(indexname),
+		WithPrefix("doc:test_" + name),
+		WithRedisClient(redisCli),
+		WithEmbedder(NewHashEmbedder(256).Embed),
+		WithVectorConfig(VectorConfig{Dim: 256, Type: VectorTypeFloat32}),
+		WithCreateIndex(true),
+	}, opts...)
+	retriever, err := NewRetriever(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return retriever
+}
+
 func TestRetrievalBasic(t *testing.T) {
 	indexname := "idx:test_retrieval_basic"
 	docprefix := "doc:test_retrieval_basic"
@@ -20,10 +62,7 @@ func TestRetrievalBasic(t *testing.T) {
 	retriever := &Retriever{
 		indexName: indexname,
 		docPrefix: docprefix,
-		redisCli: redis.NewClient(&redis.Options{
-			Addr:     cmp.Or(os.Getenv("REDIS_URL"), "localhost:6379"),
-			Protocol: 2,
-		}),
+		redisCli:  testRedis(t),
 	}
 
 	retriever.redisCli.FTDropIndexWithArgs(context.Background(), indexname, &redis.FTDropIndexOptions{DeleteDocs: true}).Result()
@@ -72,6 +111,106 @@ func TestRetrievalBasic(t *testing.T) {
 	t.Logf("index %s dropped", indexname)
 }
 
+func TestRetrievalHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_hash_embedder")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "如何重置我的密码？"},
+		{Tag: "faq", ID: "1", Content: "怎样修改收货地址？"},
+		{Tag: "faq", ID: "2", Content: "How do I reset my password?"},
+		{Tag: "how-to guide", ID: "3", Content: "重置密码的步骤"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// tags are escaped
+	docs, err := retriever.Retrieve(ctx, "我想重置密码", "how-to guide", 3, nil)
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "3", docs[0].ID)
+
+	docs, err = retriever.Retrieve(ctx, "我想重置密码", "faq", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "0", docs[0].ID)
+	should.InDelta(t, 1-docs[0].Distance/2, docs[0].Similarity, 1e-9)
+	should.Greater(t, docs[0].Similarity, 0.5)
+
+	docs, err = retriever.Retrieve(ctx, "reset password", "faq", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "2", docs[0].ID)
+
+	docs, err = retriever.Retrieve(ctx, "我想重置密码", "", 3, nil,
+		WithFilter(And(Tag(DocTag.As, "faq"), Not(Text(DocContent.As, "password")))))
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	should.Equal(t, "0", docs[0].ID)
+}
+
+func TestRetrievalRange(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_range")
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{Tag: "faq", ID: "0", Content: "如何重置我的密码？"},
+		{Tag: "faq", ID: "1", Content: "如何重置我的密码"},
+		{Tag: "faq", ID: "2", Content: "怎样修改收货地址？"},
+		{Tag: "chatter", ID: "3", Content: "如何重置我的密码？"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "如何重置我的密码？", "faq", 3, nil, WithMinSimilarity(0.9))
+	should.Nil(t, err)
+	should.Equal(t, 2, len(docs))
+	for _, doc := range docs {
+		should.GreaterOrEqual(t, doc.Similarity, 0.9)
+	}
+
+	_, err = retriever.Retrieve(ctx, "如何重置我的密码？", "", 0, nil, WithVectorRange())
+	should.ErrorIs(t, err, ErrNoRadius)
+
+	docs, err = retriever.Retrieve(ctx, "如何重置我的密码？", "", 0, nil, WithVectorRange(), WithMinSimilarity(0.9))
+	should.Nil(t, err)
+	should.Equal(t, 3, len(docs))
+	should.LessOrEqual(t, docs[0].Distance, docs[1].Distance)
+
+	docs, err = retriever.Retrieve(ctx, "如何重置我的密码？", "faq", 1, nil, WithVectorRange(), WithMaxDistance(0.2))
+	should.Nil(t, err)
+	should.Equal(t, 1, len(docs))
+	should.Equal(t, "faq", docs[0].Tag)
+}
+
+func TestRetrievalNamedVector(t *testing.T) {
+	ctx := context.Background()
+	retriever := newTestRetriever(t, "retrieval_named_vector",
+		WithEmbedder(NewHashEmbedder(128).Embed),
+		WithVectorConfig(VectorConfig{Dim: 128}),
+		WithNamedVector(NamedVector{
+			Name:     "title",
+			Config:   VectorConfig{Dim: 64, Type: VectorTypeFloat32},
+			Embedder: NewHashEmbedder(64).Embed,
+			Text:     func(doc *Document) string { return doc.Payload },
+		}))
+
+	err := retriever.StoreBatch(ctx, []*Document{
+		{ID: "0", Payload: "redis cluster", Content: "how to shard keys across slots"},
+		{ID: "1", Payload: "vector search", Content: "redis cluster keeps working when a node fails"},
+	}, nil)
+	should.Nil(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	docs, err := retriever.Retrieve(ctx, "redis cluster", "", 1, nil)
+	should.Nil(t, err)
+	should.Equal(t, "1", docs[0].ID)
+
+	docs, err = retriever.Retrieve(ctx, "redis cluster", "", 1, nil, WithVectorField("title"))
+	should.Nil(t, err)
+	should.Equal(t, "0", docs[0].ID)
+}
+
 type str2vec map[string][]float64
 
 func (s2v str2vec) Embedding(_ context.Context, text string) (vec []float64, err error) {
@@ -0,0 +1,81 @@
+package redis4rag
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scoreField is the alias KNN distances are returned and sorted under.
+const scoreField = "score"
+
+type (
+	// Score is how close a result is to the query.
+	Score struct {
+		// Distance is the raw distance reported by Redis for the metric of the vector field:
+		// 1-cosine for COSINE, 1-dot for IP and the squared euclidean distance for L2.
+		Distance float64 `json:"distance"`
+		// Similarity maps Distance to [0,1], 1 being identical, see VectorConfig.Similarity.
+		Similarity float64 `json:"similarity"`
+	}
+
+	ScoredDocument struct {
+		Document
+		Score
+		// Hybrid is only set by hybrid searches.
+		Hybrid *HybridScore `json:"hybrid,omitempty"`
+		// Window is only set by WithSentenceWindow searches.
+		Window []*Document `json:"window,omitempty"`
+		// Rerank is only set by WithReranker searches, to the score of the Reranker.
+		Rerank *float64 `json:"rerank,omitempty"`
+
+		vector []float64 // only fetched by WithMMR searches
+	}
+
+	ScoredQueryAnswer struct {
+		QueryAnswer
+		Score
+	}
+
+	ScoredChatMessage struct {
+		ChatMessage
+		Score
+	}
+)
+
+// Similarity normalizes a distance of the configured metric to [0,1]. COSINE and IP distances,
+// in [0,2] for unit vectors, map linearly to (1+cos)/2; L2 distances map to 1/(1+d).
+// IP distances of vectors longer than 1 fall outside [0,2] and are clamped.
+func (cfg VectorConfig) Similarity(distance float64) float64 {
+	switch cfg.withDefaults().Metric {
+	case DistanceMetricL2:
+		return 1 / (1 + max(distance, 0))
+	default:
+		return min(max(1-distance/2, 0), 1)
+	}
+}
+
+// distance is the inverse of Similarity, the largest distance still at least similarity similar.
+func (cfg VectorConfig) distance(similarity float64) float64 {
+	switch cfg.withDefaults().Metric {
+	case DistanceMetricL2:
+		if similarity <= 0 {
+			return math.Inf(1)
+		}
+		return 1/similarity - 1
+	default:
+		return 2 * (1 - similarity)
+	}
+}
+
+// score reads the distance a KNN query returned in doc under scoreField.
+func (cfg VectorConfig) score(doc *redis.Document) Score {
+	distance, _ := strconv.ParseFloat(doc.Fields[scoreField], 64)
+	return Score{Distance: distance, Similarity: cfg.Similarity(distance)}
+}
+
+// withScore adds the KNN distance to the fields a search returns.
+func withScore(fields []redis.FTSearchReturn) []redis.FTSearchReturn {
+	return append(fields[:len(fields):len(fields)], redis.FTSearchReturn{FieldName: scoreField})
+}
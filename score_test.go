@@ -0,0 +1,35 @@
+package redis4rag
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	cosine := VectorConfig{}
+	should.Equal(t, 1.0, cosine.Similarity(0))
+	should.Equal(t, 0.5, cosine.Similarity(1))
+	should.Equal(t, 0.0, cosine.Similarity(2))
+	ip := VectorConfig{Metric: DistanceMetricIP}
+	should.Equal(t, 0.75, ip.Similarity(0.5))
+	should.Equal(t, 1.0, ip.Similarity(-3))
+	l2 := VectorConfig{Metric: DistanceMetricL2}
+	should.Equal(t, 1.0, l2.Similarity(0))
+	should.Equal(t, 0.2, l2.Similarity(4))
+
+	doc := &redis.Document{ID: "doc:0", Fields: map[string]string{scoreField: "0.5"}}
+	should.Equal(t, Score{Distance: 0.5, Similarity: 0.75}, cosine.score(doc))
+
+	ret := withScore(DocumentDefaultReturn)
+	var returned []string
+	for _, field := range ret {
+		returned = append(returned, field.FieldName)
+	}
+	for _, field := range []string{DocId.FieldName, DocTag.FieldName, DocContent.FieldName, DocPayload.FieldName, DocMetadata, DocChunk, scoreField} {
+		should.Contains(t, returned, field)
+	}
+	// withScore leaves the list it extends alone
+	should.NotContains(t, DocumentDefaultReturn, redis.FTSearchReturn{FieldName: scoreField})
+}
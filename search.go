@@ -0,0 +1,195 @@
+package redis4rag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// SearchOption tunes a single search. The options documented for Retrieve only make the other searches fail.
+	SearchOption func(*searchOptions)
+
+	searchOptions struct {
+		vectorField   string
+		model         *EmbeddingModel
+		maxDistance   *float64
+		minSimilarity *float64
+		vectorRange   bool
+		fusion        *Fusion
+		filters       []Filter
+		parents       bool
+		window        int
+		mmr           *mmr
+		reranker      Reranker
+		rerankFactor  int
+	}
+)
+
+// ErrNoRadius is returned for a range search without WithMaxDistance or WithMinSimilarity.
+var ErrNoRadius = errors.New("redis4rag: range search needs a maximum distance or a minimum similarity")
+
+// WithVectorField searches the named vector registered with WithNamedVector instead of the content vector.
+func WithVectorField(name string) SearchOption {
+	return func(o *searchOptions) { o.vectorField = name }
+}
+
+// WithModelVersion only matches documents embedded by model, e.g. while a ReembedJob migrates them.
+func WithModelVersion(model EmbeddingModel) SearchOption {
+	return func(o *searchOptions) { o.model = &model }
+}
+
+// WithMaxDistance drops results farther than distance, in the units of Score.Distance.
+func WithMaxDistance(distance float64) SearchOption {
+	return func(o *searchOptions) { o.maxDistance = &distance }
+}
+
+// WithMinSimilarity drops results less similar than similarity, see VectorConfig.Similarity.
+func WithMinSimilarity(similarity float64) SearchOption {
+	return func(o *searchOptions) { o.minSimilarity = &similarity }
+}
+
+// WithVectorRange makes Retrieve a VECTOR_RANGE query returning every document within the radius
+// set by WithMaxDistance or WithMinSimilarity, closest first, capped at topK when topK > 0.
+func WithVectorRange() SearchOption {
+	return func(o *searchOptions) { o.vectorRange = true }
+}
+
+// WithHybrid makes Retrieve also run a BM25 full-text search for the terms of the content, fusing
+// both rankings as configured by fusion. Every result reports its sub-scores in ScoredDocument.Hybrid.
+func WithHybrid(fusion Fusion) SearchOption {
+	return func(o *searchOptions) { o.fusion = &fusion }
+}
+
+// WithFilter restricts a search to the documents matching filter, on top of its other conditions.
+// It may be given more than once, the filters being ANDed.
+func WithFilter(filter Filter) SearchOption {
+	return func(o *searchOptions) { o.filters = append(o.filters, filter) }
+}
+
+// WithParentDocument makes Retrieve search the chunks stored by StoreChunked but return their
+// parent documents, each once, scored by its best chunk.
+func WithParentDocument() SearchOption {
+	return func(o *searchOptions) { o.parents = true }
+}
+
+// WithSentenceWindow makes Retrieve return with every chunk it finds the n chunks before and after it,
+// in ScoredDocument.Window; see ScoredDocument.WindowText. It is ignored with WithParentDocument.
+func WithSentenceWindow(n int) SearchOption {
+	return func(o *searchOptions) { o.window = n }
+}
+
+// WithMMR diversifies Retrieve by maximal marginal relevance: it fetches fetchK candidates,
+// 4*topK when fetchK <= 0, with their vectors, then greedily picks the one maximizing
+// lambda*relevance - (1-lambda)*redundancy, relevance being Score.Similarity and redundancy the
+// highest cosine similarity, mapped to [0,1], to the ones already picked. lambda 1 keeps the
+// ranking of the search, lambda 0 only seeks diversity. Ties go to the better ranked candidate.
+// With WithHybrid, documents only found by the text search have neither relevance nor redundancy.
+func WithMMR(lambda float64, fetchK int) SearchOption {
+	return func(o *searchOptions) { o.mmr = &mmr{lambda: lambda, fetchK: fetchK} }
+}
+
+// WithReranker makes Retrieve fetch factor times as many candidates, 4 when factor <= 0, have reranker
+// reorder them by their relevance to the content, then keep the topK first ones. With WithMMR, it
+// reorders the documents selected by MMR instead, out of the candidates MMR fetched.
+func WithReranker(reranker Reranker, factor int) SearchOption {
+	if factor <= 0 {
+		factor = rerankOverfetch
+	}
+	return func(o *searchOptions) { o.reranker, o.rerankFactor = reranker, factor }
+}
+
+func newSearchOptions(opts ...SearchOption) *searchOptions {
+	o := &searchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// retrieverOnly rejects the options only Retrieve implements, which handle would silently ignore.
+func (o *searchOptions) retrieverOnly(handle string) error {
+	var option string
+	switch {
+	case len(o.vectorField) > 0:
+		option = "WithVectorField"
+	case o.vectorRange:
+		option = "WithVectorRange"
+	case o.fusion != nil:
+		option = "WithHybrid"
+	case o.parents:
+		option = "WithParentDocument"
+	case o.window != 0:
+		option = "WithSentenceWindow"
+	case o.mmr != nil:
+		option = "WithMMR"
+	case o.reranker != nil:
+		option = "WithReranker"
+	default:
+		return nil
+	}
+	return fmt.Errorf("redis4rag: %s does not support %s", handle, option)
+}
+
+// filter joins clauses with the ones implied by the options into a query filter, "*" when there are none.
+func (o *searchOptions) filter(clauses ...string) string {
+	if o.model != nil {
+		clauses = append(clauses, o.model.filter())
+	}
+	for _, f := range o.filters {
+		clauses = append(clauses, f.String())
+	}
+	var b strings.Builder
+	for _, clause := range clauses {
+		if len(clause) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(clause)
+	}
+	if b.Len() == 0 {
+		return "*"
+	}
+	return b.String()
+}
+
+// radius resolves the distance threshold of the options for cfg, the tighter one when both are set.
+func (o *searchOptions) radius(cfg VectorConfig) (radius float64, ok bool) {
+	if o.maxDistance != nil {
+		radius, ok = *o.maxDistance, true
+	}
+	if o.minSimilarity != nil {
+		if d := cfg.distance(*o.minSimilarity); !ok || d < radius {
+			radius, ok = d, true
+		}
+	}
+	return
+}
+
+// accept reports whether a result at distance passes the thresholds of the options.
+func (o *searchOptions) accept(cfg VectorConfig, distance float64) bool {
+	radius, ok := o.radius(cfg)
+	return !ok || distance <= radius
+}
+
+func tagClause(field string, values ...string) string {
+	escaped := make([]string, len(values))
+	for i, val := range values {
+		escaped[i] = escapeTag(val)
+	}
+	return fmt.Sprintf("@%s:{%s}", field, strings.Join(escaped, "|"))
+}
+
+// escapeTag backslash escapes the characters that are special inside a TAG query, spaces included.
+func escapeTag(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(",.<>{}[]\"':;!@#$%^&*()-+=~|/\\ ", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package redis4rag
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestSearchRadius(t *testing.T) {
+	cosine := VectorConfig{}
+	_, ok := newSearchOptions().radius(cosine)
+	should.False(t, ok)
+	should.True(t, newSearchOptions().accept(cosine, 2))
+
+	radius, ok := newSearchOptions(WithMinSimilarity(0.8)).radius(cosine)
+	should.True(t, ok)
+	should.InDelta(t, 0.4, radius, 1e-9)
+	radius, _ = newSearchOptions(WithMinSimilarity(0.8), WithMaxDistance(0.1)).radius(cosine)
+	should.Equal(t, 0.1, radius)
+	radius, _ = newSearchOptions(WithMaxDistance(0.5), WithMinSimilarity(0.8)).radius(cosine)
+	should.InDelta(t, 0.4, radius, 1e-9)
+
+	l2 := VectorConfig{Metric: DistanceMetricL2}
+	radius, _ = newSearchOptions(WithMinSimilarity(0.2)).radius(l2)
+	should.InDelta(t, 4, radius, 1e-9)
+	radius, _ = newSearchOptions(WithMinSimilarity(0)).radius(l2)
+	should.True(t, math.IsInf(radius, 1))
+	should.InDelta(t, 0.2, l2.Similarity(l2.distance(0.2)), 1e-9)
+
+	so := newSearchOptions(WithMinSimilarity(0.75))
+	should.True(t, so.accept(cosine, 0.5))
+	should.False(t, so.accept(cosine, 0.51))
+
+	should.Equal(t, "@content_vec:[VECTOR_RANGE $radius $vec]=>{$YIELD_DISTANCE_AS: score}", cosine.vectorRange("content_vec", scoreField))
+}
+
+func TestSearchRetrieverOnly(t *testing.T) {
+	should.Nil(t, newSearchOptions(WithMinSimilarity(0.8), WithFilter(Tag("tag", "a"))).retrieverOnly("LLMsCache"))
+	for option, opt := range map[string]SearchOption{
+		"WithVectorField":    WithVectorField("title"),
+		"WithVectorRange":    WithVectorRange(),
+		"WithHybrid":         WithHybrid(Fusion{}),
+		"WithParentDocument": WithParentDocument(),
+		"WithSentenceWindow": WithSentenceWindow(1),
+		"WithMMR":            WithMMR(0.5, 0),
+		"WithReranker":       WithReranker(&LexicalReranker{}, 0),
+	} {
+		should.EqualError(t, newSearchOptions(opt).retrieverOnly("LLMsCache"), "redis4rag: LLMsCache does not support "+option)
+	}
+
+	redisCli := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer redisCli.Close()
+	cache, err := NewLLMsCache(context.Background(), WithRedisClient(redisCli))
+	should.Nil(t, err)
+	_, err = cache.SemanticSearch(context.Background(), "", "query", nil, WithHybrid(Fusion{}))
+	should.EqualError(t, err, "redis4rag: LLMsCache does not support WithHybrid")
+	history, err := NewChatHistory(context.Background(), WithRedisClient(redisCli))
+	should.Nil(t, err)
+	_, err = history.SearchWithSessionId(context.Background(), 0, "session", "text", nil, WithMMR(0.5, 0))
+	should.EqualError(t, err, "redis4rag: ChatHistory does not support WithMMR")
+}
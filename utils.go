@@ -2,15 +2,160 @@ package redis4rag
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"math"
+	"strings"
 	"unsafe"
 )
 
-func vector2string(v []float64) string {
-	b := make([]byte, len(v)*8)
-	for i, e := range v {
-		i := i * 8
-		binary.LittleEndian.PutUint64(b[i:i+8], math.Float64bits(e))
+// vector2string encodes v as the little-endian blob expected by a vector field of type typ.
+func vector2string(v []float64, typ string) string {
+	var b []byte
+	switch typ {
+	case VectorTypeFloat32:
+		b = make([]byte, len(v)*4)
+		for i, e := range v {
+			i := i * 4
+			binary.LittleEndian.PutUint32(b[i:i+4], math.Float32bits(float32(e)))
+		}
+	case VectorTypeFloat16:
+		b = make([]byte, len(v)*2)
+		for i, e := range v {
+			i := i * 2
+			binary.LittleEndian.PutUint16(b[i:i+2], float16bits(float32(e)))
+		}
+	case VectorTypeBFloat16:
+		b = make([]byte, len(v)*2)
+		for i, e := range v {
+			i := i * 2
+			binary.LittleEndian.PutUint16(b[i:i+2], bfloat16bits(float32(e)))
+		}
+	default:
+		b = make([]byte, len(v)*8)
+		for i, e := range v {
+			i := i * 8
+			binary.LittleEndian.PutUint64(b[i:i+8], math.Float64bits(e))
+		}
 	}
 	return unsafe.String(unsafe.SliceData(b), len(b))
 }
+
+// string2vector decodes a blob produced by vector2string with the same type.
+func string2vector(s string, typ string) []float64 {
+	var v []float64
+	switch typ {
+	case VectorTypeFloat32:
+		v = make([]float64, len(s)/4)
+		for i := range v {
+			v[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32([]byte(s[i*4 : i*4+4]))))
+		}
+	case VectorTypeFloat16:
+		v = make([]float64, len(s)/2)
+		for i := range v {
+			v[i] = float64(float16frombits(binary.LittleEndian.Uint16([]byte(s[i*2 : i*2+2]))))
+		}
+	case VectorTypeBFloat16:
+		v = make([]float64, len(s)/2)
+		for i := range v {
+			v[i] = float64(bfloat16frombits(binary.LittleEndian.Uint16([]byte(s[i*2 : i*2+2]))))
+		}
+	default:
+		v = make([]float64, len(s)/8)
+		for i := range v {
+			v[i] = math.Float64frombits(binary.LittleEndian.Uint64([]byte(s[i*8 : i*8+8])))
+		}
+	}
+	return v
+}
+
+// quantize rounds every element of v to the precision of typ, so that the JSON document
+// holds exactly the values the vector index sees.
+func quantize(v []float64, typ string) []float64 {
+	var round func(float64) float64
+	switch typ {
+	case VectorTypeFloat32:
+		round = func(e float64) float64 { return float64(float32(e)) }
+	case VectorTypeFloat16:
+		round = func(e float64) float64 { return float64(float16frombits(float16bits(float32(e)))) }
+	case VectorTypeBFloat16:
+		round = func(e float64) float64 { return float64(bfloat16frombits(bfloat16bits(float32(e)))) }
+	default:
+		return v
+	}
+	q := make([]float64, len(v))
+	for i, e := range v {
+		q[i] = round(e)
+	}
+	return q
+}
+
+// float16bits converts f to IEEE 754 half precision, rounding to nearest even.
+func float16bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+	switch {
+	case b>>23&0xff == 0xff: // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f: // too large, Inf
+		return sign | 0x7c00
+	case exp <= 0: // subnormal or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		rounded := mant + (1 << (shift - 1)) - 1 + (mant >> shift & 1)
+		return sign | uint16(rounded>>shift)
+	}
+	// a carry out of the mantissa correctly bumps the exponent, up to Inf
+	rounded := (uint32(exp)<<23 | mant) + 0xfff + (mant >> 13 & 1)
+	return sign | uint16(rounded>>13)
+}
+
+func float16frombits(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
+
+// bfloat16bits keeps the upper half of f, rounding to nearest even.
+func bfloat16bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	if f != f {
+		return uint16(b>>16) | 0x40
+	}
+	return uint16((b + 0x7fff + (b >> 16 & 1)) >> 16)
+}
+
+func bfloat16frombits(h uint16) float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// firstMatch unwraps the first match of a JSONPath reply such as `[{"k":1}]`, which searches
+// return for object paths depending on the dialect. Other values are returned as is.
+func firstMatch(raw string) string {
+	if !strings.HasPrefix(raw, "[") {
+		return raw
+	}
+	var matches []json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &matches); err != nil || len(matches) == 0 {
+		return raw
+	}
+	return string(matches[0])
+}
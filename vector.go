@@ -0,0 +1,236 @@
+package redis4rag
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	VectorTypeBFloat16 = "BFLOAT16"
+	VectorTypeFloat16  = "FLOAT16"
+	VectorTypeFloat32  = "FLOAT32"
+	VectorTypeFloat64  = "FLOAT64"
+
+	VectorAlgorithmFlat = "FLAT"
+	VectorAlgorithmHNSW = "HNSW"
+
+	DistanceMetricL2     = "L2"
+	DistanceMetricIP     = "IP"
+	DistanceMetricCosine = "COSINE"
+)
+
+var (
+	ErrDimensionMismatch = errors.New("redis4rag: vector dimension mismatch")
+	ErrNonFiniteValue    = errors.New("redis4rag: vector contains NaN or Inf")
+	ErrZeroVector        = errors.New("redis4rag: vector is all zeros")
+)
+
+var DefaultVectorConfig = VectorConfig{
+	Dim:       1024,
+	Type:      VectorTypeFloat64,
+	Algorithm: VectorAlgorithmFlat,
+	Metric:    DistanceMetricCosine,
+}
+
+// VectorConfig describes the vector field of an index. Zero values fall back to DefaultVectorConfig.
+// Vectors are quantized to Type on the client before they are written, so the JSON documents hold
+// the values the index sees; the 16 bit types halve the vector index memory of FLOAT32.
+type VectorConfig struct {
+	Dim       int
+	Type      string
+	Algorithm string
+	Metric    string
+
+	// HNSW only
+	M              int
+	EFConstruction int
+	EFRuntime      int
+
+	// Normalize scales vectors to unit length before they are written or searched,
+	// which makes IP rank like COSINE without its per query normalization cost.
+	Normalize bool
+}
+
+// VectorError is returned for a vector rejected before it is written or searched.
+// Err is one of ErrDimensionMismatch, ErrNonFiniteValue and ErrZeroVector.
+type VectorError struct {
+	Err      error
+	Dim      int
+	Expected int
+	// Index is the offending element for ErrNonFiniteValue
+	Index int
+}
+
+func (e *VectorError) Error() string {
+	switch e.Err {
+	case ErrDimensionMismatch:
+		return fmt.Sprintf("%s: got %d, expected %d", e.Err, e.Dim, e.Expected)
+	case ErrNonFiniteValue:
+		return fmt.Sprintf("%s at index %d", e.Err, e.Index)
+	}
+	return e.Err.Error()
+}
+
+func (e *VectorError) Unwrap() error {
+	return e.Err
+}
+
+// prepare validates vec against the configured dimension, normalizes it when Normalize is set and
+// quantizes it to the configured type. vec itself is never modified.
+func (cfg VectorConfig) prepare(vec []float64) ([]float64, error) {
+	if cfg.Dim > 0 && len(vec) != cfg.Dim {
+		return nil, &VectorError{Err: ErrDimensionMismatch, Dim: len(vec), Expected: cfg.Dim}
+	}
+	var norm float64
+	for i, v := range vec {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, &VectorError{Err: ErrNonFiniteValue, Dim: len(vec), Expected: cfg.Dim, Index: i}
+		}
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil, &VectorError{Err: ErrZeroVector, Dim: len(vec), Expected: cfg.Dim}
+	}
+	if cfg.Normalize {
+		norm = math.Sqrt(norm)
+		normalized := make([]float64, len(vec))
+		for i, v := range vec {
+			normalized[i] = v / norm
+		}
+		vec = normalized
+	}
+	vec = quantize(vec, cfg.Type)
+	for i, v := range vec {
+		// out of the range of a 16 bit type
+		if math.IsInf(v, 0) {
+			return nil, &VectorError{Err: ErrNonFiniteValue, Dim: len(vec), Expected: cfg.Dim, Index: i}
+		}
+	}
+	return vec, nil
+}
+
+// jsonValue is what a prepared vector is written to JSON as: float32 elements, printed shorter,
+// unless the type needs float64 precision.
+func (cfg VectorConfig) jsonValue(vec []float64) interface{} {
+	if cfg.Type == VectorTypeFloat64 || len(cfg.Type) == 0 {
+		return vec
+	}
+	v := make([]float32, len(vec))
+	for i, e := range vec {
+		v[i] = float32(e)
+	}
+	return v
+}
+
+// RetypeSchema returns a copy of schema whose vector fields use typ.
+func RetypeSchema(schema []*redis.FieldSchema, typ string) []*redis.FieldSchema {
+	retyped := make([]*redis.FieldSchema, len(schema))
+	for i, field := range schema {
+		if cfg, ok := vectorConfigOf([]*redis.FieldSchema{field}); ok {
+			cfg.Type = typ
+			field = cfg.Field(field.FieldName, field.As)
+		}
+		retyped[i] = field
+	}
+	return retyped
+}
+
+func (cfg VectorConfig) withDefaults() VectorConfig {
+	if cfg.Dim == 0 {
+		cfg.Dim = DefaultVectorConfig.Dim
+	}
+	if cfg.Type == "" {
+		cfg.Type = DefaultVectorConfig.Type
+	}
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = DefaultVectorConfig.Algorithm
+	}
+	if cfg.Metric == "" {
+		cfg.Metric = DefaultVectorConfig.Metric
+	}
+	return cfg
+}
+
+func (cfg VectorConfig) Validate() error {
+	cfg = cfg.withDefaults()
+	if cfg.Dim < 0 {
+		return fmt.Errorf("redis4rag: invalid vector dimension %d", cfg.Dim)
+	}
+	switch cfg.Type {
+	case VectorTypeBFloat16, VectorTypeFloat16, VectorTypeFloat32, VectorTypeFloat64:
+	default:
+		return fmt.Errorf("redis4rag: unsupported vector type %q", cfg.Type)
+	}
+	switch cfg.Algorithm {
+	case VectorAlgorithmFlat, VectorAlgorithmHNSW:
+	default:
+		return fmt.Errorf("redis4rag: unsupported vector algorithm %q", cfg.Algorithm)
+	}
+	switch cfg.Metric {
+	case DistanceMetricL2, DistanceMetricIP, DistanceMetricCosine:
+	default:
+		return fmt.Errorf("redis4rag: unsupported distance metric %q", cfg.Metric)
+	}
+	return nil
+}
+
+func (cfg VectorConfig) VectorArgs() *redis.FTVectorArgs {
+	cfg = cfg.withDefaults()
+	if cfg.Algorithm == VectorAlgorithmHNSW {
+		return &redis.FTVectorArgs{
+			HNSWOptions: &redis.FTHNSWOptions{
+				Type:                   cfg.Type,
+				Dim:                    cfg.Dim,
+				DistanceMetric:         cfg.Metric,
+				MaxEdgesPerNode:        cfg.M,
+				MaxAllowedEdgesPerNode: cfg.EFConstruction,
+				EFRunTime:              cfg.EFRuntime,
+			},
+		}
+	}
+	return &redis.FTVectorArgs{
+		FlatOptions: &redis.FTFlatOptions{
+			Type:           cfg.Type,
+			Dim:            cfg.Dim,
+			DistanceMetric: cfg.Metric,
+		},
+	}
+}
+
+func (cfg VectorConfig) Field(fieldName, as string) *redis.FieldSchema {
+	return &redis.FieldSchema{FieldName: fieldName, As: as, FieldType: redis.SearchFieldTypeVector, VectorArgs: cfg.VectorArgs()}
+}
+
+// knn renders the KNN clause of a hybrid query, e.g. "KNN 3 @content_vec $vec EF_RUNTIME 10 AS score".
+func (cfg VectorConfig) knn(k int, field, as string) string {
+	if cfg.Algorithm == VectorAlgorithmHNSW && cfg.EFRuntime > 0 {
+		return fmt.Sprintf("KNN %d @%s $vec EF_RUNTIME %d AS %s", k, field, cfg.EFRuntime, as)
+	}
+	return fmt.Sprintf("KNN %d @%s $vec AS %s", k, field, as)
+}
+
+// vectorRange renders a range clause yielding the distance as as, e.g.
+// "@content_vec:[VECTOR_RANGE $radius $vec]=>{$YIELD_DISTANCE_AS: score}".
+func (cfg VectorConfig) vectorRange(field, as string) string {
+	return fmt.Sprintf("@%s:[VECTOR_RANGE $radius $vec]=>{$YIELD_DISTANCE_AS: %s}", field, as)
+}
+
+// vectorConfigOf recovers the VectorConfig of the first vector field in schema.
+func vectorConfigOf(schema []*redis.FieldSchema) (cfg VectorConfig, ok bool) {
+	for _, field := range schema {
+		if field.FieldType != redis.SearchFieldTypeVector || field.VectorArgs == nil {
+			continue
+		}
+		if flat := field.VectorArgs.FlatOptions; flat != nil {
+			return VectorConfig{Dim: flat.Dim, Type: flat.Type, Algorithm: VectorAlgorithmFlat, Metric: flat.DistanceMetric}, true
+		}
+		if hnsw := field.VectorArgs.HNSWOptions; hnsw != nil {
+			return VectorConfig{Dim: hnsw.Dim, Type: hnsw.Type, Algorithm: VectorAlgorithmHNSW, Metric: hnsw.DistanceMetric,
+				M: hnsw.MaxEdgesPerNode, EFConstruction: hnsw.MaxAllowedEdgesPerNode, EFRuntime: hnsw.EFRunTime}, true
+		}
+	}
+	return
+}
@@ -0,0 +1,117 @@
+package redis4rag
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestVectorConfig(t *testing.T) {
+	should.Nil(t, VectorConfig{}.Validate())
+	should.NotNil(t, VectorConfig{Type: "INT8"}.Validate())
+	should.NotNil(t, VectorConfig{Algorithm: "IVF"}.Validate())
+	should.NotNil(t, VectorConfig{Metric: "HAMMING"}.Validate())
+
+	hnsw := VectorConfig{Dim: 384, Type: VectorTypeFloat32, Algorithm: VectorAlgorithmHNSW, Metric: DistanceMetricIP,
+		M: 16, EFConstruction: 200, EFRuntime: 10}
+	args := hnsw.VectorArgs()
+	should.Nil(t, args.FlatOptions)
+	should.Equal(t, 384, args.HNSWOptions.Dim)
+	should.Equal(t, 16, args.HNSWOptions.MaxEdgesPerNode)
+	should.Equal(t, 200, args.HNSWOptions.MaxAllowedEdgesPerNode)
+	should.Equal(t, "KNN 3 @content_vec $vec EF_RUNTIME 10 AS score", hnsw.knn(3, "content_vec", "score"))
+
+	schema := hnsw.DocumentSchema()
+	should.Equal(t, len(DocumentSchema), len(schema))
+	cfg, ok := vectorConfigOf(schema)
+	should.True(t, ok)
+	should.Equal(t, hnsw, cfg)
+
+	cfg, ok = vectorConfigOf(ChatHistorySchema)
+	should.True(t, ok)
+	should.Equal(t, DefaultVectorConfig, cfg)
+	should.Equal(t, "KNN 1 @query_vec $vec AS score", cfg.knn(1, "query_vec", "score"))
+
+	should.Equal(t, 3*4, len(vector2string([]float64{1, 2, 3}, VectorTypeFloat32)))
+	should.Equal(t, 3*8, len(vector2string([]float64{1, 2, 3}, VectorTypeFloat64)))
+}
+
+func TestVectorPrepare(t *testing.T) {
+	cfg := VectorConfig{Dim: 3}
+	vec, err := cfg.prepare([]float64{3, 0, 4})
+	should.Nil(t, err)
+	should.Equal(t, []float64{3, 0, 4}, vec)
+
+	_, err = cfg.prepare([]float64{1, 2})
+	should.ErrorIs(t, err, ErrDimensionMismatch)
+	should.EqualError(t, err, "redis4rag: vector dimension mismatch: got 2, expected 3")
+
+	_, err = cfg.prepare([]float64{1, math.NaN(), 1})
+	var vecErr *VectorError
+	should.ErrorAs(t, err, &vecErr)
+	should.Equal(t, ErrNonFiniteValue, vecErr.Err)
+	should.Equal(t, 1, vecErr.Index)
+
+	_, err = cfg.prepare([]float64{1, 1, math.Inf(-1)})
+	should.ErrorIs(t, err, ErrNonFiniteValue)
+
+	_, err = cfg.prepare([]float64{0, 0, 0})
+	should.ErrorIs(t, err, ErrZeroVector)
+
+	cfg.Normalize = true
+	input := []float64{3, 0, 4}
+	vec, err = cfg.prepare(input)
+	should.Nil(t, err)
+	should.Equal(t, []float64{0.6, 0, 0.8}, vec)
+	should.Equal(t, []float64{3, 0, 4}, input)
+
+	retriever := &Retriever{vector: VectorConfig{Dim: 3}}
+	err = retriever.write(context.Background(), nil, &Document{ID: "0"}, []float64{1}, nil)
+	should.ErrorIs(t, err, ErrDimensionMismatch)
+}
+
+func TestVectorQuantize(t *testing.T) {
+	should.Equal(t, uint16(0x3c00), float16bits(1))
+	should.Equal(t, uint16(0xc000), float16bits(-2))
+	should.Equal(t, uint16(0x7bff), float16bits(65504))
+	should.Equal(t, uint16(0x7c00), float16bits(65520))
+	should.Equal(t, uint16(0x0001), float16bits(0x1p-24))
+	should.Equal(t, uint16(0x3c00), float16bits(1+0x1p-11)) // a tie rounds to even
+	should.Equal(t, uint16(0x3c02), float16bits(1+3*0x1p-11))
+	should.Equal(t, float32(0x1p-24), float16frombits(0x0001))
+	should.Equal(t, uint16(0x3f80), bfloat16bits(1))
+	should.Equal(t, uint16(0x3f81), bfloat16bits(1+0x1.8p-8))
+
+	vec := []float64{0.5, -1.25, 3, 1e-3}
+	for _, typ := range []string{VectorTypeFloat64, VectorTypeFloat32, VectorTypeFloat16, VectorTypeBFloat16} {
+		q := quantize(vec, typ)
+		should.Equal(t, q, string2vector(vector2string(q, typ), typ), typ)
+		should.InDeltaSlice(t, vec, q, 1e-2, typ)
+	}
+	should.Equal(t, 8, len(vector2string(vec, VectorTypeFloat16)))
+
+	cfg := VectorConfig{Dim: 2, Type: VectorTypeFloat16}
+	vec, err := cfg.prepare([]float64{1, 1.0001})
+	should.Nil(t, err)
+	should.Equal(t, []float64{1, 1}, vec)
+	should.Equal(t, []float32{1, 1}, cfg.jsonValue(vec))
+	_, err = cfg.prepare([]float64{1, 1e5})
+	should.ErrorIs(t, err, ErrNonFiniteValue)
+	vec, err = VectorConfig{Dim: 2, Type: VectorTypeBFloat16}.prepare([]float64{1, 1e5})
+	should.Nil(t, err)
+	should.Equal(t, []float64{1, 99840}, vec)
+}
+
+func TestRetypeSchema(t *testing.T) {
+	schema := VectorConfig{Dim: 8, Algorithm: VectorAlgorithmHNSW, M: 16}.DocumentSchema()
+	retyped := RetypeSchema(schema, VectorTypeFloat16)
+	should.Equal(t, len(schema), len(retyped))
+	cfg, ok := vectorConfigOf(retyped)
+	should.True(t, ok)
+	should.Equal(t, VectorConfig{Dim: 8, Type: VectorTypeFloat16, Algorithm: VectorAlgorithmHNSW, Metric: DistanceMetricCosine, M: 16}, cfg)
+	cfg, _ = vectorConfigOf(schema)
+	should.Equal(t, VectorTypeFloat64, cfg.Type)
+	should.Equal(t, schema[0], retyped[0])
+}
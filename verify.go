@@ -0,0 +1,196 @@
+package redis4rag
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	// SchemaMismatch is one difference between an index and its expected schema.
+	// Field is the attribute name, empty for index level properties such as "prefixes".
+	SchemaMismatch struct {
+		Field    string
+		Property string
+		Expected string
+		Actual   string
+	}
+
+	// SchemaDiff lists every mismatch found by VerifyIndex; it is also usable as an error.
+	SchemaDiff []SchemaMismatch
+
+	indexInfo struct {
+		keyType    string
+		prefixes   []string
+		attributes map[string]map[string]string
+	}
+)
+
+func (diff SchemaDiff) Error() string {
+	var b strings.Builder
+	b.WriteString("redis4rag: index schema mismatch:")
+	for _, m := range diff {
+		b.WriteString(" ")
+		b.WriteString(m.String())
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+func (m SchemaMismatch) String() string {
+	if len(m.Field) > 0 {
+		return fmt.Sprintf("%s.%s expected %q, got %q", m.Field, m.Property, m.Expected, m.Actual)
+	}
+	return fmt.Sprintf("%s expected %q, got %q", m.Property, m.Expected, m.Actual)
+}
+
+// VerifyIndex reads FT.INFO of index (or alias) and compares its attributes, vector dimension,
+// type and metric, and prefixes against schema and prefix. An empty diff means the index matches.
+func VerifyIndex(ctx context.Context, cli redis.UniversalClient, index string, schema []*redis.FieldSchema, prefix []interface{}) (diff SchemaDiff, err error) {
+	var raw interface{}
+	if raw, err = cli.Do(ctx, "FT.INFO", index).Result(); err != nil {
+		return
+	}
+	var info *indexInfo
+	if info, err = parseIndexInfo(raw); err != nil {
+		return
+	}
+	return diffSchema(info, schema, prefix), nil
+}
+
+func diffSchema(info *indexInfo, schema []*redis.FieldSchema, prefix []interface{}) (diff SchemaDiff) {
+	if !strings.EqualFold(info.keyType, "JSON") {
+		diff = append(diff, SchemaMismatch{Property: "key_type", Expected: "JSON", Actual: info.keyType})
+	}
+	expectedPrefixes := make([]string, 0, len(prefix))
+	for _, p := range prefix {
+		expectedPrefixes = append(expectedPrefixes, fmt.Sprint(p))
+	}
+	actualPrefixes := slices.Clone(info.prefixes)
+	slices.Sort(expectedPrefixes)
+	slices.Sort(actualPrefixes)
+	if !slices.Equal(expectedPrefixes, actualPrefixes) {
+		diff = append(diff, SchemaMismatch{Property: "prefixes",
+			Expected: strings.Join(expectedPrefixes, ","), Actual: strings.Join(actualPrefixes, ",")})
+	}
+
+	seen := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		name := field.As
+		if len(name) == 0 {
+			name = field.FieldName
+		}
+		seen[name] = true
+		attr, ok := info.attributes[name]
+		if !ok {
+			diff = append(diff, SchemaMismatch{Field: name, Property: "attribute", Expected: field.FieldType.String()})
+			continue
+		}
+		expected := map[string]string{
+			"identifier": field.FieldName,
+			"type":       field.FieldType.String(),
+		}
+		if cfg, ok := vectorConfigOf([]*redis.FieldSchema{field}); ok {
+			expected["algorithm"] = cfg.Algorithm
+			expected["data_type"] = cfg.Type
+			expected["dim"] = strconv.Itoa(cfg.Dim)
+			expected["distance_metric"] = cfg.Metric
+		}
+		for _, property := range []string{"identifier", "type", "algorithm", "data_type", "dim", "distance_metric"} {
+			want, ok := expected[property]
+			if !ok {
+				continue
+			}
+			if got := attr[property]; !strings.EqualFold(want, got) {
+				diff = append(diff, SchemaMismatch{Field: name, Property: property, Expected: want, Actual: got})
+			}
+		}
+	}
+	names := make([]string, 0, len(info.attributes))
+	for name := range info.attributes {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		diff = append(diff, SchemaMismatch{Field: name, Property: "attribute", Actual: info.attributes[name]["type"]})
+	}
+	return
+}
+
+// parseIndexInfo reads the raw FT.INFO reply, which is a flat key/value list on RESP2 and a map on RESP3.
+func parseIndexInfo(raw interface{}) (*indexInfo, error) {
+	top, err := infoPairs(raw)
+	if err != nil {
+		return nil, err
+	}
+	info := &indexInfo{attributes: map[string]map[string]string{}}
+	if def, ok := top["index_definition"]; ok {
+		pairs, err := infoPairs(def)
+		if err != nil {
+			return nil, err
+		}
+		info.keyType = fmt.Sprint(pairs["key_type"])
+		if prefixes, ok := pairs["prefixes"].([]interface{}); ok {
+			for _, p := range prefixes {
+				info.prefixes = append(info.prefixes, fmt.Sprint(p))
+			}
+		}
+	}
+	attributes, _ := top["attributes"].([]interface{})
+	for _, a := range attributes {
+		attr := parseAttribute(a)
+		info.attributes[attr["attribute"]] = attr
+	}
+	return info, nil
+}
+
+// attribute properties followed by a value; anything else in the list is a flag such as SORTABLE
+var attributeValueKeys = map[string]bool{
+	"identifier": true, "attribute": true, "type": true, "weight": true, "separator": true, "phonetic": true,
+	"algorithm": true, "data_type": true, "dim": true, "distance_metric": true,
+	"m": true, "ef_construction": true, "ef_runtime": true, "epsilon": true,
+}
+
+func parseAttribute(raw interface{}) map[string]string {
+	attr := map[string]string{}
+	switch v := raw.(type) {
+	case []interface{}:
+		for i := 0; i < len(v); i++ {
+			key := strings.ToLower(fmt.Sprint(v[i]))
+			if attributeValueKeys[key] && i+1 < len(v) {
+				attr[key] = fmt.Sprint(v[i+1])
+				i++
+			} else {
+				attr[key] = "true"
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, val := range v {
+			attr[strings.ToLower(fmt.Sprint(key))] = fmt.Sprint(val)
+		}
+	}
+	return attr
+}
+
+func infoPairs(raw interface{}) (map[string]interface{}, error) {
+	pairs := map[string]interface{}{}
+	switch v := raw.(type) {
+	case []interface{}:
+		for i := 0; i+1 < len(v); i += 2 {
+			pairs[fmt.Sprint(v[i])] = v[i+1]
+		}
+	case map[interface{}]interface{}:
+		for key, val := range v {
+			pairs[fmt.Sprint(key)] = val
+		}
+	default:
+		return nil, fmt.Errorf("redis4rag: unexpected FT.INFO reply %T", raw)
+	}
+	return pairs, nil
+}
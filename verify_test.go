@@ -0,0 +1,56 @@
+package redis4rag
+
+import (
+	"errors"
+	"testing"
+
+	should "github.com/stretchr/testify/assert"
+)
+
+func TestVerifyIndexDiff(t *testing.T) {
+	// RESP2 FT.INFO reply of an LLM cache index created with a 768-d L2 vector field
+	raw := []interface{}{
+		"index_name", "idx:test_verify",
+		"index_options", []interface{}{},
+		"index_definition", []interface{}{
+			"key_type", "JSON",
+			"prefixes", []interface{}{"doc:test_verify"},
+			"default_score", "1",
+		},
+		"attributes", []interface{}{
+			[]interface{}{"identifier", "$.tag", "attribute", "tag", "type", "TAG", "SEPARATOR", ","},
+			[]interface{}{"identifier", "$.query", "attribute", "query", "type", "TEXT", "WEIGHT", "1", "NOSTEM"},
+			[]interface{}{"identifier", "$.answer", "attribute", "answer", "type", "TEXT", "WEIGHT", "1", "NOINDEX"},
+			[]interface{}{"identifier", "$.query_vec", "attribute", "query_vec", "type", "VECTOR",
+				"algorithm", "FLAT", "data_type", "FLOAT64", "dim", int64(768), "distance_metric", "L2"},
+			[]interface{}{"identifier", "$.embedding.model", "attribute", "embedding_model", "type", "TAG", "SEPARATOR", ","},
+			[]interface{}{"identifier", "$.embedding.version", "attribute", "embedding_version", "type", "TAG", "SEPARATOR", ","},
+			[]interface{}{"identifier", "$.extra", "attribute", "extra", "type", "NUMERIC"},
+		},
+		"num_docs", "0",
+	}
+	info, err := parseIndexInfo(raw)
+	should.Nil(t, err)
+	should.Equal(t, "JSON", info.keyType)
+	should.Equal(t, []string{"doc:test_verify"}, info.prefixes)
+	should.Equal(t, "true", info.attributes["query"]["nostem"])
+	should.Equal(t, "768", info.attributes["query_vec"]["dim"])
+
+	diff := diffSchema(info, LLMCacheSchema, []interface{}{"doc:test_verify"})
+	should.Equal(t, SchemaDiff{
+		{Field: "query_vec", Property: "dim", Expected: "1024", Actual: "768"},
+		{Field: "query_vec", Property: "distance_metric", Expected: "COSINE", Actual: "L2"},
+		{Field: "extra", Property: "attribute", Actual: "NUMERIC"},
+	}, diff)
+
+	diff = diffSchema(info, VectorConfig{Dim: 768, Metric: DistanceMetricL2}.LLMCacheSchema(), []interface{}{"doc:other"})
+	should.Equal(t, SchemaDiff{
+		{Property: "prefixes", Expected: "doc:other", Actual: "doc:test_verify"},
+		{Field: "extra", Property: "attribute", Actual: "NUMERIC"},
+	}, diff)
+
+	var err2 error = diff
+	var target SchemaDiff
+	should.True(t, errors.As(err2, &target))
+	should.Contains(t, err2.Error(), `prefixes expected "doc:other", got "doc:test_verify"`)
+}